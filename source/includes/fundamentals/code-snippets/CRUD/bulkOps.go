@@ -58,6 +58,9 @@ func main() {
 	}
 
 	bookInsert, err := bookColl.InsertMany(context.TODO(), books)
+	if err != nil {
+		panic(err)
+	}
 	poemInsert, err := poemColl.InsertMany(context.TODO(), poems)
 	//end insertDocs
 	if err != nil {
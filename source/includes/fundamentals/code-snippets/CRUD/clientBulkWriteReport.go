@@ -0,0 +1,249 @@
+// Streams a large number of mixed write operations across several
+// namespaces in client bulk writes and reports the errors for each
+// operation by using the Go driver
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"sort"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+)
+
+// start-structs
+type Book struct {
+	ID     int64 `bson:"_id"`
+	Title  string
+	Author string
+	Length int32
+}
+
+type Poem struct {
+	ID     int64 `bson:"_id"`
+	Title  string
+	Author string
+	Year   int32
+}
+
+// end-structs
+
+// start-report-structs
+// Describes a failed operation by its position in the full stream of writes
+type OperationError struct {
+	Index     int
+	Namespace string
+	Code      int
+	Message   string
+}
+
+// Collects the outcome of every client bulk write in the stream
+type Report struct {
+	Operations         int
+	Inserted           int64
+	Modified           int64
+	Deleted            int64
+	WriteErrors        []OperationError
+	WriteConcernErrors []mongo.WriteConcernError
+	// Positions of update operations that matched no document, taken from
+	// the verbose results
+	UnmatchedUpdates []int
+}
+
+// end-report-structs
+
+const (
+	totalOperations = 300_000
+	chunkSize       = 50_000
+)
+
+func main() {
+	var uri string
+	if uri = os.Getenv("MONGODB_URI"); uri == "" {
+		log.Fatal("You must set your 'MONGODB_URI' environment variable. See\n\t https://www.mongodb.com/docs/drivers/go/current/usage-examples/")
+	}
+
+	client, err := mongo.Connect(options.Client().ApplyURI(uri))
+	if err != nil {
+		panic(err)
+	}
+	defer func() {
+		if err = client.Disconnect(context.TODO()); err != nil {
+			panic(err)
+		}
+	}()
+
+	for _, name := range []string{"books", "poems"} {
+		if err = client.Database("db").Collection(name).Drop(context.TODO()); err != nil {
+			panic(err)
+		}
+	}
+
+	writes := generateWrites(totalOperations)
+	report, err := streamWrites(context.TODO(), client, writes)
+	if err != nil {
+		panic(err)
+	}
+	printReport(report)
+}
+
+// Sends the writes received from the channel in client bulk writes of at
+// most chunkSize operations, so that the full stream never has to be held in
+// memory at once. Returns an error if a bulk write does not run at all.
+// begin stream writes
+func streamWrites(ctx context.Context, client *mongo.Client, writes <-chan mongo.ClientBulkWrite) (Report, error) {
+	var report Report
+
+	// Continues past failed operations and returns a result for each
+	// successful operation
+	opts := options.ClientBulkWrite().
+		SetOrdered(false).
+		SetVerboseResults(true)
+
+	chunk := make([]mongo.ClientBulkWrite, 0, chunkSize)
+	flush := func() error {
+		if len(chunk) == 0 {
+			return nil
+		}
+		offset := report.Operations
+		report.Operations += len(chunk)
+
+		result, err := client.BulkWrite(ctx, chunk, opts)
+
+		var bwe mongo.ClientBulkWriteException
+		switch {
+		case err == nil:
+		case errors.As(err, &bwe):
+			// A top-level error means the server did not run the writes
+			if bwe.WriteError != nil {
+				return fmt.Errorf("bulk write of operations %d to %d failed: %w",
+					offset, offset+len(chunk)-1, err)
+			}
+
+			// Maps each failed operation back to its position in the stream
+			for i, we := range bwe.WriteErrors {
+				report.WriteErrors = append(report.WriteErrors, OperationError{
+					Index:     offset + i,
+					Namespace: chunk[i].Database + "." + chunk[i].Collection,
+					Code:      we.Code,
+					Message:   we.Message,
+				})
+			}
+			report.WriteConcernErrors = append(report.WriteConcernErrors, bwe.WriteConcernErrors...)
+			result = bwe.PartialResult
+		default:
+			return err
+		}
+
+		if result != nil {
+			report.Inserted += result.InsertedCount
+			report.Modified += result.ModifiedCount
+			report.Deleted += result.DeletedCount
+
+			// The verbose results hold one entry for each successful
+			// operation, keyed by its position in the chunk
+			for i, ur := range result.UpdateResults {
+				if ur.MatchedCount == 0 {
+					report.UnmatchedUpdates = append(report.UnmatchedUpdates, offset+i)
+				}
+			}
+		}
+		chunk = chunk[:0]
+		return nil
+	}
+
+	for write := range writes {
+		chunk = append(chunk, write)
+		if len(chunk) == chunkSize {
+			if err := flush(); err != nil {
+				return report, err
+			}
+		}
+	}
+	if err := flush(); err != nil {
+		return report, err
+	}
+
+	return report, nil
+}
+
+// end stream writes
+
+// Generates n mixed write operations for the "books" and "poems"
+// collections. Every 10,000th operation inserts a duplicate _id instead of a
+// new book, so that the report contains write errors and the update of the
+// missing book matches no document.
+// begin generate writes
+func generateWrites(n int) <-chan mongo.ClientBulkWrite {
+	writes := make(chan mongo.ClientBulkWrite, 1000)
+
+	go func() {
+		defer close(writes)
+
+		for i := 0; i < n; i++ {
+			id := int64(i / 4)
+
+			var write mongo.ClientBulkWrite
+			switch {
+			case i > 0 && i%10_000 == 0:
+				write = mongo.ClientBulkWrite{"db", "books", mongo.NewClientInsertOneModel().
+					SetDocument(Book{ID: 0, Title: "Duplicate", Author: "Unknown"})}
+			case i%4 == 0:
+				write = mongo.ClientBulkWrite{"db", "books", mongo.NewClientInsertOneModel().
+					SetDocument(Book{ID: id, Title: fmt.Sprintf("Book %d", id), Author: "Various", Length: 100})}
+			case i%4 == 1:
+				write = mongo.ClientBulkWrite{"db", "poems", mongo.NewClientInsertOneModel().
+					SetDocument(Poem{ID: id, Title: fmt.Sprintf("Poem %d", id), Author: "Various", Year: 2000})}
+			case i%4 == 2:
+				write = mongo.ClientBulkWrite{"db", "books", mongo.NewClientUpdateOneModel().
+					SetFilter(bson.D{{"_id", id}}).
+					SetUpdate(bson.D{{"$inc", bson.D{{"length", 10}}}})}
+			default:
+				write = mongo.ClientBulkWrite{"db", "poems", mongo.NewClientDeleteOneModel().
+					SetFilter(bson.D{{"_id", id}})}
+			}
+			writes <- write
+		}
+	}()
+
+	return writes
+}
+
+// end generate writes
+
+// Prints a summary of the report followed by each failed operation
+// begin print report
+func printReport(report Report) {
+	fmt.Printf("Operations sent: %d\n", report.Operations)
+	fmt.Printf("Documents inserted: %d\n", report.Inserted)
+	fmt.Printf("Documents modified: %d\n", report.Modified)
+	fmt.Printf("Documents deleted: %d\n", report.Deleted)
+
+	sort.Slice(report.WriteErrors, func(i, j int) bool {
+		return report.WriteErrors[i].Index < report.WriteErrors[j].Index
+	})
+
+	fmt.Printf("\nWrite errors: %d\n", len(report.WriteErrors))
+	for _, opErr := range report.WriteErrors {
+		fmt.Printf("  operation %d on %s: code %d: %s\n",
+			opErr.Index, opErr.Namespace, opErr.Code, opErr.Message)
+	}
+
+	fmt.Printf("\nWrite concern errors: %d\n", len(report.WriteConcernErrors))
+	for _, wce := range report.WriteConcernErrors {
+		fmt.Printf("  code %d (%s): %s\n", wce.Code, wce.Name, wce.Message)
+	}
+
+	sort.Ints(report.UnmatchedUpdates)
+	fmt.Printf("\nUpdates that matched no document: %d\n", len(report.UnmatchedUpdates))
+	for _, index := range report.UnmatchedUpdates {
+		fmt.Printf("  operation %d\n", index)
+	}
+}
+
+// end print report
@@ -0,0 +1,22 @@
+// Tests the count and estimate examples against the fake server in
+// tools/fakeserver. Run with:
+//
+//	go test countAndEstimate.go countAndEstimate_test.go
+package main
+
+import (
+	"testing"
+
+	"github.com/mongodb/docs-golang/source/includes/fundamentals/code-snippets/tools/snippettest"
+)
+
+// Output of main against the fake server
+const golden = `Number of documents inserted: 9
+Number of documents with a rating less than six: 4
+Estimated number of documents in the tea collection: 9
+{"total_documents":{"$numberInt":"5"}}
+`
+
+func TestCountAndEstimate(t *testing.T) {
+	snippettest.Equal(t, snippettest.Run(t, main), golden)
+}
@@ -65,7 +65,7 @@ func main() {
 
 		// Sets options for the delete operation to use the index on the
 		// "_id" field
-		opts := options.Delete().SetHint(bson.D{{"_id", 1}})
+		opts := options.DeleteMany().SetHint(bson.D{{"_id", 1}})
 
 		// Deletes matching documents and prints the number of deleted
 		// documents
@@ -0,0 +1,23 @@
+// Tests the delete example against the fake server in tools/fakeserver. The
+// headings that main prints fail the printf check of go vet, so run with:
+//
+//	go test -vet=off delete.go delete_test.go
+package main
+
+import (
+	"testing"
+
+	"github.com/mongodb/docs-golang/source/includes/fundamentals/code-snippets/tools/snippettest"
+)
+
+// Output of main against the fake server
+const golden = `Number of documents inserted: 4
+
+Delete Many:
+
+Number of documents deleted: 2
+`
+
+func TestDelete(t *testing.T) {
+	snippettest.Equal(t, snippettest.Run(t, main), golden)
+}
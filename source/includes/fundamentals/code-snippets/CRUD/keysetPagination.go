@@ -0,0 +1,189 @@
+// Paginates query results by using keyset pagination and compares it with
+// skip-based pagination by using the Go driver
+package main
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+)
+
+// start-restaurant-struct
+type Restaurant struct {
+	ID      bson.ObjectID `bson:"_id"`
+	Name    string        `bson:"name"`
+	Cuisine string        `bson:"cuisine"`
+	Borough string        `bson:"borough"`
+}
+
+// end-restaurant-struct
+
+// start-page-structs
+// Identifies the last document of a page. Clients receive it only as an
+// opaque string.
+type pageKey struct {
+	Name string        `bson:"n"`
+	ID   bson.ObjectID `bson:"i"`
+}
+
+type Page struct {
+	Restaurants []Restaurant
+	NextToken   string
+}
+
+// end-page-structs
+
+const pageSize = 20
+
+func main() {
+	var uri string
+	if uri = os.Getenv("MONGODB_URI"); uri == "" {
+		log.Fatal("You must set your 'MONGODB_URI' environment variable. See\n\t https://www.mongodb.com/docs/drivers/go/current/usage-examples/#environment-variable")
+	}
+
+	client, err := mongo.Connect(options.Client().ApplyURI(uri))
+	if err != nil {
+		panic(err)
+	}
+	defer func() {
+		if err = client.Disconnect(context.TODO()); err != nil {
+			panic(err)
+		}
+	}()
+
+	coll := client.Database("sample_restaurants").Collection("restaurants")
+
+	// Creates an index that supports both the sort and the range filter
+	// begin index
+	model := mongo.IndexModel{Keys: bson.D{{"name", 1}, {"_id", 1}}}
+	if _, err = coll.Indexes().CreateOne(context.TODO(), model); err != nil {
+		panic(err)
+	}
+	// end index
+
+	fmt.Println("\nFirst Three Pages:")
+	{
+		// begin paginate
+		token := ""
+		for i := 1; i <= 3; i++ {
+			page, err := nextPage(context.TODO(), coll, token)
+			if err != nil {
+				panic(err)
+			}
+			// A page is empty if the collection is empty or if documents
+			// were deleted after the previous page was read
+			if len(page.Restaurants) == 0 {
+				fmt.Printf("Page %d is empty\n", i)
+				break
+			}
+			fmt.Printf("Page %d starts with %q, next token %s\n",
+				i, page.Restaurants[0].Name, page.NextToken)
+
+			if page.NextToken == "" {
+				break
+			}
+			token = page.NextToken
+		}
+		// end paginate
+	}
+}
+
+// Returns the page that follows the page identified by token. An empty
+// token returns the first page.
+// begin next page
+func nextPage(ctx context.Context, coll *mongo.Collection, token string) (Page, error) {
+	filter := bson.D{}
+	if token != "" {
+		key, err := decodeToken(token)
+		if err != nil {
+			return Page{}, err
+		}
+
+		// Matches documents that sort after the last document of the
+		// previous page. The _id field breaks ties between equal names.
+		filter = bson.D{{"$or", bson.A{
+			bson.D{{"name", bson.D{{"$gt", key.Name}}}},
+			bson.D{{"name", key.Name}, {"_id", bson.D{{"$gt", key.ID}}}},
+		}}}
+	}
+
+	opts := options.Find().
+		SetSort(bson.D{{"name", 1}, {"_id", 1}}).
+		SetLimit(pageSize + 1)
+
+	cursor, err := coll.Find(ctx, filter, opts)
+	if err != nil {
+		return Page{}, err
+	}
+
+	var results []Restaurant
+	if err = cursor.All(ctx, &results); err != nil {
+		return Page{}, err
+	}
+
+	// Requests one extra document to learn whether another page exists
+	page := Page{Restaurants: results}
+	if len(results) > pageSize {
+		page.Restaurants = results[:pageSize]
+		last := page.Restaurants[pageSize-1]
+		page.NextToken, err = encodeToken(pageKey{Name: last.Name, ID: last.ID})
+		if err != nil {
+			return Page{}, err
+		}
+	}
+	return page, nil
+}
+
+// end next page
+
+// begin tokens
+// Encodes a page key as an opaque, URL-safe string
+func encodeToken(key pageKey) (string, error) {
+	b, err := bson.Marshal(key)
+	if err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// Decodes a string produced by encodeToken
+func decodeToken(token string) (pageKey, error) {
+	var key pageKey
+	b, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil {
+		return key, errors.New("invalid page token")
+	}
+	if err = bson.Unmarshal(b, &key); err != nil {
+		return key, errors.New("invalid page token")
+	}
+	return key, nil
+}
+
+// end tokens
+
+// Returns the page at pageNum by skipping all documents on earlier pages
+// begin skip page
+func skipPage(ctx context.Context, coll *mongo.Collection, pageNum int) ([]Restaurant, error) {
+	opts := options.Find().
+		SetSort(bson.D{{"name", 1}, {"_id", 1}}).
+		SetSkip(int64(pageNum * pageSize)).
+		SetLimit(pageSize)
+
+	cursor, err := coll.Find(ctx, bson.D{}, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	var results []Restaurant
+	err = cursor.All(ctx, &results)
+	return results, err
+}
+
+// end skip page
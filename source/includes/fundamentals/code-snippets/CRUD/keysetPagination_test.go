@@ -0,0 +1,93 @@
+// Compares the latency of skip-based and keyset pagination at increasing
+// page depths. Run with:
+//
+//	MONGODB_URI=<connection string> go test -bench . keysetPagination.go keysetPagination_test.go
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"testing"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+)
+
+func BenchmarkDeepPages(b *testing.B) {
+	uri := os.Getenv("MONGODB_URI")
+	if uri == "" {
+		b.Skip("set MONGODB_URI to a deployment that has the sample_restaurants dataset")
+	}
+
+	client, err := mongo.Connect(options.Client().ApplyURI(uri))
+	if err != nil {
+		b.Fatal(err)
+	}
+	defer client.Disconnect(context.Background())
+
+	ctx := context.Background()
+	coll := client.Database("sample_restaurants").Collection("restaurants")
+	model := mongo.IndexModel{Keys: bson.D{{"name", 1}, {"_id", 1}}}
+	if _, err = coll.Indexes().CreateOne(ctx, model); err != nil {
+		b.Fatal(err)
+	}
+
+	// Collects the token that starts each page so that the keyset query
+	// can jump directly to a deep page
+	tokens := []string{""}
+	for {
+		page, err := nextPage(ctx, coll, tokens[len(tokens)-1])
+		if err != nil {
+			b.Fatal(err)
+		}
+		if page.NextToken == "" {
+			break
+		}
+		tokens = append(tokens, page.NextToken)
+	}
+
+	for _, pageNum := range []int{1, 10, 100, 1000, len(tokens) - 1} {
+		if pageNum >= len(tokens) {
+			continue
+		}
+
+		b.Run(fmt.Sprintf("skip/page=%d", pageNum), func(b *testing.B) {
+			for b.Loop() {
+				if _, err := skipPage(ctx, coll, pageNum); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+		b.Run(fmt.Sprintf("keyset/page=%d", pageNum), func(b *testing.B) {
+			for b.Loop() {
+				if _, err := nextPage(ctx, coll, tokens[pageNum]); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+// Checks that page tokens round-trip and that malformed tokens are rejected
+func TestPageTokens(t *testing.T) {
+	key := pageKey{Name: "Café Nuovo", ID: bson.NewObjectID()}
+	token, err := encodeToken(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := decodeToken(token)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got != key {
+		t.Errorf("got %+v, want %+v", got, key)
+	}
+
+	for _, bad := range []string{"not base64!", "AAAA"} {
+		if _, err := decodeToken(bad); err == nil {
+			t.Errorf("decodeToken(%q) succeeded, want an error", bad)
+		}
+	}
+}
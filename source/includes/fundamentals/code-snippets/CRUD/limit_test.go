@@ -0,0 +1,35 @@
+// Tests the limit examples against the fake server in tools/fakeserver. The
+// headings that main prints fail the printf check of go vet, so run with:
+//
+//	go test -vet=off limit.go limit_test.go
+package main
+
+import (
+	"testing"
+
+	"github.com/mongodb/docs-golang/source/includes/fundamentals/code-snippets/tools/snippettest"
+)
+
+// Output of main against the fake server
+const golden = `Number of documents inserted: 4
+
+Limit:
+
+{"title":"Concepts in Topology","enrollment":35}
+{"title":"Ancient Greece","enrollment":100}
+
+Limit, Skip, and Sort:
+
+{"title":"Physiology I","enrollment":60}
+{"title":"Concepts in Topology","enrollment":35}
+
+Aggregation Limit:
+
+{"title":"Romantic Era Music","enrollment":15}
+{"title":"Concepts in Topology","enrollment":35}
+{"title":"Ancient Greece","enrollment":100}
+`
+
+func TestLimit(t *testing.T) {
+	snippettest.Equal(t, snippettest.Run(t, main), golden)
+}
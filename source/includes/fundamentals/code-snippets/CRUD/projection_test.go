@@ -0,0 +1,35 @@
+// Tests the projection examples against the fake server in tools/fakeserver.
+// The headings that main prints fail the printf check of go vet, so run
+// with:
+//
+//	go test -vet=off projection.go projection_test.go
+package main
+
+import (
+	"testing"
+
+	"github.com/mongodb/docs-golang/source/includes/fundamentals/code-snippets/tools/snippettest"
+)
+
+// Output of main against the fake server
+const golden = `Number of documents inserted: 2
+
+Exclude Projection:
+
+{"title":"Primate Behavior"}
+{"title":"Revolution and Reform"}
+
+Include Projection:
+
+{"title":"Primate Behavior","enrollment":40}
+{"title":"Revolution and Reform","enrollment":12}
+
+Aggregation Projection:
+
+{"title":"Primate Behavior","course_id":"PSY2030"}
+{"title":"Revolution and Reform","course_id":"HIST3080"}
+`
+
+func TestProjection(t *testing.T) {
+	snippettest.Equal(t, snippettest.Run(t, main), golden)
+}
@@ -0,0 +1,172 @@
+// Performs CRUD operations through the generic Repository type of the
+// repository package by using the Go driver
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"time"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+
+	"github.com/mongodb/docs-golang/source/includes/fundamentals/code-snippets/repository"
+)
+
+// start-tea-struct
+type Tea struct {
+	ID        bson.ObjectID `bson:"_id,omitempty"`
+	Type      string        `bson:"type"`
+	Rating    int32         `bson:"rating"`
+	Vendor    []string      `bson:"vendor,omitempty"`
+	CreatedAt time.Time     `bson:"created_at,omitempty"`
+	UpdatedAt time.Time     `bson:"updated_at,omitempty"`
+}
+
+// end-tea-struct
+
+func main() {
+	var uri string
+	if uri = os.Getenv("MONGODB_URI"); uri == "" {
+		log.Fatal("You must set your 'MONGODB_URI' environment variable. See\n\t https://www.mongodb.com/docs/drivers/go/current/usage-examples/")
+	}
+
+	client, err := mongo.Connect(options.Client().ApplyURI(uri))
+	if err != nil {
+		panic(err)
+	}
+	defer func() {
+		if err = client.Disconnect(context.TODO()); err != nil {
+			panic(err)
+		}
+	}()
+
+	coll := client.Database("db").Collection("tea")
+	_ = coll.Drop(context.TODO())
+
+	// begin new repository
+	teas := repository.New[Tea](coll, repository.Options{SoftDelete: true, Timestamps: true})
+	// end new repository
+
+	fmt.Println("Create:")
+	var masalaID interface{}
+	{
+		// begin create
+		docs := []Tea{
+			{Type: "Masala", Rating: 10, Vendor: []string{"A", "C"}},
+			{Type: "English Breakfast", Rating: 6},
+			{Type: "Oolong", Rating: 7, Vendor: []string{"C"}},
+			{Type: "Assam", Rating: 5},
+			{Type: "Earl Grey", Rating: 8, Vendor: []string{"A", "B"}},
+		}
+		for _, doc := range docs {
+			id, err := teas.Create(context.TODO(), doc)
+			if err != nil {
+				panic(err)
+			}
+			fmt.Printf("Inserted %s with ID %v\n", doc.Type, id)
+			if doc.Type == "Masala" {
+				masalaID = id
+			}
+		}
+		// end create
+	}
+
+	fmt.Println("\nGet:")
+	{
+		// begin get
+		tea, err := teas.Get(context.TODO(), masalaID)
+		if err != nil {
+			panic(err)
+		}
+		fmt.Printf("%s has rating %d and was created at %v\n", tea.Type, tea.Rating, tea.CreatedAt)
+		// end get
+	}
+
+	fmt.Println("\nList:")
+	{
+		// Retrieves the second page of teas rated above 5, two teas per
+		// page, from highest to lowest rating
+		// begin list
+		filter := bson.D{{"rating", bson.D{{"$gt", 5}}}}
+		page, err := teas.List(context.TODO(), filter, repository.ListOptions{
+			Sort:     bson.D{{"rating", -1}},
+			Page:     2,
+			PageSize: 2,
+		})
+		if err != nil {
+			panic(err)
+		}
+		for _, tea := range page {
+			fmt.Printf("%s: %d\n", tea.Type, tea.Rating)
+		}
+		// end list
+	}
+
+	fmt.Println("\nUpdate:")
+	{
+		// begin update
+		err := teas.Update(context.TODO(), masalaID, bson.D{{"$inc", bson.D{{"rating", -1}}}})
+		if err != nil {
+			panic(err)
+		}
+		// end update
+
+		tea, err := teas.Get(context.TODO(), masalaID)
+		if err != nil {
+			panic(err)
+		}
+		fmt.Printf("%s now has rating %d, updated at %v\n", tea.Type, tea.Rating, tea.UpdatedAt)
+	}
+
+	fmt.Println("\nUpsert:")
+	{
+		// begin upsert
+		inserted, err := teas.Upsert(context.TODO(),
+			bson.D{{"type", "Matcha"}},
+			Tea{Type: "Matcha", Rating: 9, Vendor: []string{"D"}})
+		if err != nil {
+			panic(err)
+		}
+		fmt.Printf("Inserted Matcha: %v\n", inserted)
+
+		inserted, err = teas.Upsert(context.TODO(),
+			bson.D{{"type", "Matcha"}},
+			Tea{Type: "Matcha", Rating: 10, Vendor: []string{"D"}})
+		if err != nil {
+			panic(err)
+		}
+		fmt.Printf("Inserted Matcha again: %v\n", inserted)
+		// end upsert
+
+		matcha, err := teas.List(context.TODO(), bson.D{{"type", "Matcha"}}, repository.ListOptions{})
+		if err != nil {
+			panic(err)
+		}
+		for _, tea := range matcha {
+			fmt.Printf("%s: %d\n", tea.Type, tea.Rating)
+		}
+	}
+
+	fmt.Println("\nDelete:")
+	{
+		// begin delete
+		if err := teas.Delete(context.TODO(), masalaID); err != nil {
+			panic(err)
+		}
+
+		_, err := teas.Get(context.TODO(), masalaID)
+		fmt.Printf("Get after delete returned: %v\n", err)
+		// end delete
+
+		// The document remains in the collection with a "deleted_at" field
+		count, err := coll.CountDocuments(context.TODO(), bson.D{{"deleted_at", bson.D{{"$exists", true}}}})
+		if err != nil {
+			panic(err)
+		}
+		fmt.Printf("Soft-deleted documents in the collection: %d\n", count)
+	}
+}
@@ -0,0 +1,47 @@
+// Tests the find and aggregation examples against the fake server in
+// tools/fakeserver. The headings that main prints fail the printf check of
+// go vet, so run with:
+//
+//	go test -vet=off retrieve.go retrieve_test.go
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/mongodb/docs-golang/source/includes/fundamentals/code-snippets/tools/snippettest"
+)
+
+// Output of main against the fake server
+const golden = `Number of documents inserted: 6
+
+Find:
+
+{"item":"Sencha","rating":7,"date_ordered":{"$date":"2009-11-18T00:00:00Z"}}
+{"item":"Masala","rating":8,"date_ordered":{"$date":"2009-12-01T00:00:00Z"}}
+
+Find One:
+
+{"item":"Masala","rating":9,"date_ordered":{"$date":"2009-11-12T00:00:00Z"}}
+
+Find One by ObjectId:
+
+No documents found
+{}
+
+Aggregation:
+
+Masala had an average rating of 9
+Sencha had an average rating of 8.5
+Hibiscus had an average rating of 4
+`
+
+func TestRetrieve(t *testing.T) {
+	// Prints the order dates, which main creates in the local time zone,
+	// the same way on every machine
+	local := time.Local
+	time.Local = time.UTC
+	defer func() { time.Local = local }()
+
+	snippettest.Equal(t, snippettest.Run(t, main), golden)
+}
@@ -0,0 +1,308 @@
+// Validates documents with a $jsonSchema validator and reports why the server
+// rejected a document by using the Go driver
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+)
+
+// start-details-structs
+// Holds the errInfo.details document that the server returns when a
+// document fails $jsonSchema validation
+type ValidationDetails struct {
+	OperatorName            string       `bson:"operatorName"`
+	Title                   string       `bson:"title,omitempty"`
+	SchemaRulesNotSatisfied []SchemaRule `bson:"schemaRulesNotSatisfied"`
+}
+
+// Describes one schema keyword that the document did not satisfy
+type SchemaRule struct {
+	OperatorName           string          `bson:"operatorName"`
+	Reason                 string          `bson:"reason,omitempty"`
+	SpecifiedAs            bson.Raw        `bson:"specifiedAs,omitempty"`
+	ConsideredValue        interface{}     `bson:"consideredValue,omitempty"`
+	ConsideredType         string          `bson:"consideredType,omitempty"`
+	MissingProperties      []string        `bson:"missingProperties,omitempty"`
+	AdditionalProperties   []string        `bson:"additionalProperties,omitempty"`
+	PropertiesNotSatisfied []PropertyRules `bson:"propertiesNotSatisfied,omitempty"`
+}
+
+// Lists the rules that one property did not satisfy
+type PropertyRules struct {
+	PropertyName string       `bson:"propertyName"`
+	Description  string       `bson:"description,omitempty"`
+	Details      []SchemaRule `bson:"details"`
+}
+
+// end-details-structs
+
+func main() {
+	var uri string
+	if uri = os.Getenv("MONGODB_URI"); uri == "" {
+		log.Fatal("You must set your 'MONGODB_URI' environment variable. See\n\t https://www.mongodb.com/docs/drivers/go/current/usage-examples/")
+	}
+
+	client, err := mongo.Connect(options.Client().ApplyURI(uri))
+	if err != nil {
+		panic(err)
+	}
+	defer func() {
+		if err = client.Disconnect(context.TODO()); err != nil {
+			panic(err)
+		}
+	}()
+
+	db := client.Database("db")
+	coll := db.Collection("users")
+	_ = coll.Drop(context.TODO())
+
+	fmt.Println("Create Collection:")
+	{
+		// Creates a collection whose validator requires a name, an email
+		// address, and an age, and rejects fields that the schema does not
+		// list
+		// begin create validated coll
+		validator := bson.D{{"$jsonSchema", bson.D{
+			{"bsonType", "object"},
+			{"title", "User validation"},
+			{"required", bson.A{"name", "email", "age"}},
+			{"additionalProperties", false},
+			{"properties", bson.D{
+				{"_id", bson.D{{"bsonType", "objectId"}}},
+				{"name", bson.D{
+					{"bsonType", "string"},
+					{"description", "must be a string"},
+				}},
+				{"email", bson.D{
+					{"bsonType", "string"},
+					{"pattern", "^[^@]+@[^@]+$"},
+					{"description", "must be an email address"},
+				}},
+				{"age", bson.D{
+					{"bsonType", "int"},
+					{"minimum", 0},
+					{"maximum", 150},
+					{"description", "must be an integer from 0 to 150"},
+				}},
+				{"status", bson.D{
+					{"enum", bson.A{"active", "suspended"}},
+				}},
+			}},
+		}}}
+
+		opts := options.CreateCollection().
+			SetValidator(validator).
+			SetValidationLevel("strict").
+			SetValidationAction("error")
+
+		if err = db.CreateCollection(context.TODO(), "users", opts); err != nil {
+			panic(err)
+		}
+		// end create validated coll
+		fmt.Println("Created the users collection with a $jsonSchema validator")
+	}
+
+	fmt.Println("\nRejected Inserts:")
+	{
+		docs := []bson.D{
+			{{"name", "Ana"}, {"email", "ana@example.com"}, {"age", 34}, {"status", "active"}},
+			{{"name", "Ben"}, {"email", "ben.example.com"}, {"age", -3}},
+			{{"name", "Cai"}, {"age", 29}, {"nickname", "cc"}},
+			{{"name", 42}, {"email", "dee@example.com"}, {"age", 51}, {"status", "deleted"}},
+		}
+
+		// begin rejected inserts
+		for _, doc := range docs {
+			_, err := coll.InsertOne(context.TODO(), doc)
+			if err == nil {
+				fmt.Printf("Inserted %v\n", doc)
+				continue
+			}
+
+			var we mongo.WriteException
+			if !errors.As(err, &we) {
+				panic(err)
+			}
+			for _, writeErr := range we.WriteErrors {
+				// Code 121 is DocumentValidationFailure
+				if writeErr.Code != 121 {
+					panic(err)
+				}
+				fmt.Printf("Rejected %v\n%s", doc, validationReport(writeErr.Details))
+			}
+		}
+		// end rejected inserts
+	}
+
+	fmt.Println("\nValidation Action:")
+	{
+		// Changes the validation action so that the server logs a warning
+		// for an invalid document instead of rejecting it
+		// begin warn action
+		command := bson.D{
+			{"collMod", "users"},
+			{"validationAction", "warn"},
+		}
+		if err = db.RunCommand(context.TODO(), command).Err(); err != nil {
+			panic(err)
+		}
+
+		_, err = coll.InsertOne(context.TODO(), bson.D{{"name", "Eve"}, {"age", 200}})
+		fmt.Printf("Insert with the warn action returned error: %v\n", err)
+		// end warn action
+
+		command = bson.D{
+			{"collMod", "users"},
+			{"validationAction", "error"},
+		}
+		if err = db.RunCommand(context.TODO(), command).Err(); err != nil {
+			panic(err)
+		}
+	}
+
+	fmt.Println("\nValidation Level:")
+	{
+		// With the "moderate" level, the server does not validate updates
+		// to documents that already fail validation, such as the document
+		// inserted with the warn action
+		// begin moderate level
+		command := bson.D{
+			{"collMod", "users"},
+			{"validationLevel", "moderate"},
+		}
+		if err = db.RunCommand(context.TODO(), command).Err(); err != nil {
+			panic(err)
+		}
+
+		result, err := coll.UpdateOne(context.TODO(),
+			bson.D{{"name", "Eve"}},
+			bson.D{{"$set", bson.D{{"age", 201}}}})
+		if err != nil {
+			panic(err)
+		}
+		fmt.Printf("Updated %d invalid document with the moderate level\n", result.ModifiedCount)
+		// end moderate level
+
+		command = bson.D{
+			{"collMod", "users"},
+			{"validationLevel", "strict"},
+		}
+		if err = db.RunCommand(context.TODO(), command).Err(); err != nil {
+			panic(err)
+		}
+	}
+
+	fmt.Println("\nBypass Validation:")
+	{
+		doc := bson.D{{"name", "Finn"}, {"age", "unknown"}}
+
+		_, err = coll.InsertOne(context.TODO(), doc)
+		fmt.Printf("Insert without bypass returned error: %v\n", err != nil)
+
+		// Inserts a document that fails validation by setting the bypass
+		// option, which requires the bypassDocumentValidation privilege
+		// begin bypass validation
+		opts := options.InsertOne().SetBypassDocumentValidation(true)
+		result, err := coll.InsertOne(context.TODO(), doc, opts)
+		if err != nil {
+			panic(err)
+		}
+		fmt.Printf("Inserted %v with bypass\n", result.InsertedID)
+		// end bypass validation
+
+		// Finds the documents that do not match the validator
+		// begin find invalid
+		specs, err := db.ListCollectionSpecifications(context.TODO(), bson.D{{"name", "users"}})
+		if err != nil {
+			panic(err)
+		}
+		var collOpts struct {
+			Validator bson.Raw `bson:"validator"`
+		}
+		if err = bson.Unmarshal(specs[0].Options, &collOpts); err != nil {
+			panic(err)
+		}
+
+		count, err := coll.CountDocuments(context.TODO(), bson.D{{"$nor", bson.A{collOpts.Validator}}})
+		if err != nil {
+			panic(err)
+		}
+		fmt.Printf("%d documents do not match the validator\n", count)
+		// end find invalid
+	}
+}
+
+// start-validation-report
+// Formats the errInfo.details document of a validation error as one line for
+// each rule that the document did not satisfy
+func validationReport(details bson.Raw) string {
+	var d ValidationDetails
+	if err := bson.Unmarshal(details, &d); err != nil {
+		return fmt.Sprintf("  cannot decode details: %v\n  %s\n", err, details)
+	}
+
+	var b strings.Builder
+	for _, rule := range d.SchemaRulesNotSatisfied {
+		writeRule(&b, "", rule)
+	}
+	return b.String()
+}
+
+// Writes rule and any nested property rules, prefixing each line with the
+// dotted path of the property
+func writeRule(b *strings.Builder, path string, rule SchemaRule) {
+	switch {
+	case len(rule.PropertiesNotSatisfied) > 0:
+		for _, prop := range rule.PropertiesNotSatisfied {
+			field := prop.PropertyName
+			if path != "" {
+				field = path + "." + field
+			}
+			for _, detail := range prop.Details {
+				writeRule(b, field, detail)
+			}
+		}
+		return
+	case len(rule.MissingProperties) > 0:
+		fmt.Fprintf(b, "  %s: missing %s\n", pathOrDoc(path), strings.Join(rule.MissingProperties, ", "))
+		return
+	case len(rule.AdditionalProperties) > 0:
+		fmt.Fprintf(b, "  %s: unexpected %s\n", pathOrDoc(path), strings.Join(rule.AdditionalProperties, ", "))
+		return
+	}
+
+	line := fmt.Sprintf("  %s: %s", pathOrDoc(path), rule.OperatorName)
+	if rule.Reason != "" {
+		line += " " + rule.Reason
+	}
+	if rule.ConsideredValue != nil {
+		line += fmt.Sprintf(", got %v", rule.ConsideredValue)
+	}
+	if rule.ConsideredType != "" {
+		line += fmt.Sprintf(" of type %s", rule.ConsideredType)
+	}
+	if len(rule.SpecifiedAs) > 0 {
+		if specified, err := bson.MarshalExtJSON(rule.SpecifiedAs, false, false); err == nil {
+			line += fmt.Sprintf(", want %s", specified)
+		}
+	}
+	fmt.Fprintln(b, line)
+}
+
+func pathOrDoc(path string) string {
+	if path == "" {
+		return "(document)"
+	}
+	return path
+}
+
+// end-validation-report
@@ -0,0 +1,28 @@
+// Tests the skip examples against the fake server in tools/fakeserver. The
+// headings that main prints fail the printf check of go vet, so run with:
+//
+//	go test -vet=off skip.go skip_test.go
+package main
+
+import (
+	"testing"
+
+	"github.com/mongodb/docs-golang/source/includes/fundamentals/code-snippets/tools/snippettest"
+)
+
+// Output of main against the fake server
+const golden = `Number of documents inserted: 4
+
+Skip:
+
+{"title":"Plate Tectonics","enrollment":45}
+{"title":"Abstract Algebra","enrollment":60}
+Aggegation Skip:
+{"title":"Plate Tectonics","enrollment":45}
+{"title":"World Fiction","enrollment":35}
+{"title":"Modern Poetry","enrollment":12}
+`
+
+func TestSkip(t *testing.T) {
+	snippettest.Equal(t, snippettest.Run(t, main), golden)
+}
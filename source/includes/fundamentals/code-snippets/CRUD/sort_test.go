@@ -0,0 +1,47 @@
+// Tests the sort examples against the fake server in tools/fakeserver. The
+// headings that main prints fail the printf check of go vet, so run with:
+//
+//	go test -vet=off sort.go sort_test.go
+package main
+
+import (
+	"testing"
+
+	"github.com/mongodb/docs-golang/source/includes/fundamentals/code-snippets/tools/snippettest"
+)
+
+// Output of main against the fake server
+const golden = `Number of documents inserted: 4
+
+Ascending Sort:
+
+{"title":"Modern Poetry","enrollment":12}
+{"title":"World Fiction","enrollment":35}
+{"title":"Plate Tectonics","enrollment":35}
+{"title":"Abstract Algebra","enrollment":60}
+
+Descending Sort:
+
+{"title":"Abstract Algebra","enrollment":60}
+{"title":"World Fiction","enrollment":35}
+{"title":"Plate Tectonics","enrollment":35}
+{"title":"Modern Poetry","enrollment":12}
+
+Multi Sort:
+
+{"title":"Abstract Algebra","enrollment":60}
+{"title":"Plate Tectonics","enrollment":35}
+{"title":"World Fiction","enrollment":35}
+{"title":"Modern Poetry","enrollment":12}
+
+Aggregation Sort:
+
+{"title":"Abstract Algebra","enrollment":60}
+{"title":"Plate Tectonics","enrollment":35}
+{"title":"World Fiction","enrollment":35}
+{"title":"Modern Poetry","enrollment":12}
+`
+
+func TestSort(t *testing.T) {
+	snippettest.Equal(t, snippettest.Run(t, main), golden)
+}
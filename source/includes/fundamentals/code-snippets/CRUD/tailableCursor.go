@@ -0,0 +1,213 @@
+// Follows new documents in a capped collection with a tailable cursor by
+// using the Go driver
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"os/signal"
+	"time"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+)
+
+// start-log-entry-struct
+type LogEntry struct {
+	ID bson.ObjectID `bson:"_id"`
+	// Position of the entry in insertion order
+	Seq     int64
+	Level   string
+	Message string
+}
+
+// end-log-entry-struct
+
+func main() {
+	var uri string
+	if uri = os.Getenv("MONGODB_URI"); uri == "" {
+		log.Fatal("You must set your 'MONGODB_URI' environment variable. See\n\t https://www.mongodb.com/docs/drivers/go/current/usage-examples/")
+	}
+
+	client, err := mongo.Connect(options.Client().ApplyURI(uri))
+	if err != nil {
+		panic(err)
+	}
+	defer func() {
+		if err = client.Disconnect(context.TODO()); err != nil {
+			panic(err)
+		}
+	}()
+
+	// Creates a capped collection that holds at most 1 MB of log entries
+	// begin create capped
+	db := client.Database("db")
+	createOpts := options.CreateCollection().
+		SetCapped(true).
+		SetSizeInBytes(1024 * 1024)
+
+	err = db.CreateCollection(context.TODO(), "logs", createOpts)
+	if err != nil && !isNamespaceExists(err) {
+		panic(err)
+	}
+	coll := db.Collection("logs")
+	// end create capped
+
+	// Continues the sequence of entries from an earlier run
+	var last LogEntry
+	err = coll.FindOne(context.TODO(), bson.D{}, options.FindOne().SetSort(bson.D{{"seq", -1}})).Decode(&last)
+	if err != nil && !errors.Is(err, mongo.ErrNoDocuments) {
+		panic(err)
+	}
+
+	// A tailable cursor on an empty collection is closed immediately, so
+	// the collection starts with one entry
+	_, err = coll.InsertOne(context.TODO(), LogEntry{
+		ID:      bson.NewObjectID(),
+		Seq:     last.Seq + 1,
+		Level:   "INFO",
+		Message: "log started",
+	})
+	if err != nil {
+		panic(err)
+	}
+
+	// Stops tailing when the user presses Ctrl+C
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	go produce(ctx, coll, last.Seq+2)
+
+	// Prints the entry above and the entries that follow it, but not the
+	// entries from earlier runs
+	if err = tail(ctx, coll, last.Seq); err != nil {
+		panic(err)
+	}
+	fmt.Println("Stopped tailing the collection")
+}
+
+// Prints each entry in coll with a seq greater than lastSeq until ctx is
+// canceled. Reopens the cursor after the last printed entry when the server
+// closes it or a resumable error ends it, and returns any other error.
+func tail(ctx context.Context, coll *mongo.Collection, lastSeq int64) error {
+	for {
+		// begin tailable cursor
+		filter := bson.D{{"seq", bson.D{{"$gt", lastSeq}}}}
+
+		// Opens a cursor that waits up to 2 seconds for new documents on
+		// each getMore before it returns an empty batch
+		opts := options.Find().
+			SetCursorType(options.TailableAwait).
+			SetMaxAwaitTime(2 * time.Second)
+
+		cursor, err := coll.Find(ctx, filter, opts)
+		// end tailable cursor
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			if !isResumable(err) {
+				return err
+			}
+			fmt.Printf("Find failed, retrying: %v\n", err)
+		} else if err = follow(ctx, cursor, &lastSeq); err != nil {
+			return err
+		}
+		if ctx.Err() != nil {
+			return nil
+		}
+
+		// Waits briefly before reopening so that a collection with no
+		// matching documents is not polled in a tight loop
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-time.After(time.Second):
+		}
+	}
+}
+
+// Prints the entries from cursor and records the seq of each in lastSeq.
+// Returns nil when ctx is canceled or the cursor can be reopened, and
+// returns an error otherwise.
+func follow(ctx context.Context, cursor *mongo.Cursor, lastSeq *int64) error {
+	defer cursor.Close(context.TODO())
+
+	// begin try next
+	for {
+		if cursor.TryNext(ctx) {
+			var entry LogEntry
+			if err := cursor.Decode(&entry); err != nil {
+				return err
+			}
+			fmt.Printf("[%s] %s\n", entry.Level, entry.Message)
+			*lastSeq = entry.Seq
+			continue
+		}
+
+		// TryNext returns false when the batch is empty, when ctx is
+		// canceled, or when the cursor is no longer usable
+		if ctx.Err() != nil {
+			return nil
+		}
+		if err := cursor.Err(); err != nil {
+			if !isResumable(err) {
+				return err
+			}
+			fmt.Printf("Cursor failed, reopening: %v\n", err)
+			return nil
+		}
+		if cursor.ID() == 0 {
+			fmt.Println("Cursor closed by the server, reopening")
+			return nil
+		}
+	}
+	// end try next
+}
+
+// Inserts a log entry every 500 milliseconds until ctx is canceled,
+// numbering the entries from seq
+func produce(ctx context.Context, coll *mongo.Collection, seq int64) {
+	ticker := time.NewTicker(500 * time.Millisecond)
+	defer ticker.Stop()
+
+	for i := 1; ; i, seq = i+1, seq+1 {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		_, err := coll.InsertOne(ctx, LogEntry{
+			ID:      bson.NewObjectID(),
+			Seq:     seq,
+			Level:   "INFO",
+			Message: fmt.Sprintf("request %d handled", i),
+		})
+		if err != nil && ctx.Err() == nil {
+			log.Printf("Failed to insert log entry: %v", err)
+		}
+	}
+}
+
+// Reports whether a tailable cursor that failed with err can be reopened:
+// network errors, and the CursorNotFound, CappedPositionLost,
+// QueryPlanKilled, and CursorKilled server errors
+func isResumable(err error) bool {
+	if mongo.IsNetworkError(err) {
+		return true
+	}
+	var se mongo.ServerError
+	return errors.As(err, &se) &&
+		(se.HasErrorCode(43) || se.HasErrorCode(136) || se.HasErrorCode(175) || se.HasErrorCode(237))
+}
+
+// Reports whether err indicates that the collection already exists
+func isNamespaceExists(err error) bool {
+	var cmdErr mongo.CommandError
+	return errors.As(err, &cmdErr) && cmdErr.HasErrorCode(48)
+}
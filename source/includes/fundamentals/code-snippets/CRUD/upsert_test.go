@@ -0,0 +1,31 @@
+// Tests the upsert example against the fake server in tools/fakeserver. The
+// headings that main prints fail the printf check of go vet, so run with:
+//
+//	go test -vet=off upsert.go upsert_test.go
+package main
+
+import (
+	"testing"
+
+	"github.com/mongodb/docs-golang/source/includes/fundamentals/code-snippets/tools/snippettest"
+)
+
+// Output of main against the fake server
+const golden = `Number of documents inserted: 3
+
+Upsert:
+
+Number of documents updated: 0
+Number of documents upserted: 1
+
+All Documents in Collection:
+
+{"species":"Polyscias fruticosa","plant_id":1,"height":27.6}
+{"species":"Polyscias fruticosa","plant_id":2,"height":34.9}
+{"species":"Ledebouria socialis","plant_id":1,"height":11.4}
+{"species":"Ledebouria socialis","plant_id":3,"height":8.3}
+`
+
+func TestUpsert(t *testing.T) {
+	snippettest.Equal(t, snippettest.Run(t, main), golden)
+}
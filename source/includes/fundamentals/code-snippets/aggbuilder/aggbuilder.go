@@ -0,0 +1,172 @@
+// Package aggbuilder builds aggregation pipelines with typed helper
+// functions instead of nested bson.D literals. Each helper returns the same
+// bson.D value that you would write by hand, so built stages can be mixed
+// with raw stages.
+//
+// Example:
+//
+//	pipeline := aggbuilder.Pipeline(
+//		aggbuilder.Match(bson.D{{"toppings", "milk foam"}}),
+//		aggbuilder.Sort(aggbuilder.Asc("price")),
+//		aggbuilder.Limit(2),
+//	)
+//	cursor, err := coll.Aggregate(ctx, pipeline)
+package aggbuilder
+
+import (
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+)
+
+// Combines stages into a pipeline
+func Pipeline(stages ...bson.D) mongo.Pipeline {
+	return mongo.Pipeline(stages)
+}
+
+// Returns a field path expression, such as "$price" for "price"
+func Field(name string) string {
+	return "$" + name
+}
+
+// Stages
+
+// Returns a $match stage that passes documents that match filter
+func Match(filter bson.D) bson.D {
+	return bson.D{{"$match", filter}}
+}
+
+// Groups documents by id and computes each field with an accumulator
+func Group(id interface{}, fields ...bson.E) bson.D {
+	return bson.D{{"$group", append(bson.D{{"_id", id}}, fields...)}}
+}
+
+// Returns a $sort stage that sorts by keys in order
+func Sort(keys ...bson.E) bson.D {
+	return bson.D{{"$sort", bson.D(keys)}}
+}
+
+// Returns a $project stage that includes, excludes, or computes fields
+func Project(fields ...bson.E) bson.D {
+	return bson.D{{"$project", bson.D(fields)}}
+}
+
+// Returns an $unset stage that removes fields
+func Unset(fields ...string) bson.D {
+	a := make(bson.A, len(fields))
+	for i, f := range fields {
+		a[i] = f
+	}
+	return bson.D{{"$unset", a}}
+}
+
+// Joins documents from the "from" collection whose foreignField equals
+// localField
+func Lookup(from, localField, foreignField, as string) bson.D {
+	return bson.D{{"$lookup", bson.D{
+		{"from", from},
+		{"localField", localField},
+		{"foreignField", foreignField},
+		{"as", as},
+	}}}
+}
+
+// Joins the results of running pipeline on the "from" collection. The
+// pipeline can refer to the variables in let.
+func LookupPipeline(from string, let bson.D, pipeline mongo.Pipeline, as string) bson.D {
+	return bson.D{{"$lookup", bson.D{
+		{"from", from},
+		{"let", let},
+		{"pipeline", pipeline},
+		{"as", as},
+	}}}
+}
+
+// Returns an $unwind stage that outputs one document for each element of
+// the array at path
+func Unwind(path string) bson.D {
+	return bson.D{{"$unwind", Field(path)}}
+}
+
+// Returns a $limit stage that passes the first n documents
+func Limit(n int) bson.D {
+	return bson.D{{"$limit", n}}
+}
+
+// Returns a $skip stage that drops the first n documents
+func Skip(n int) bson.D {
+	return bson.D{{"$skip", n}}
+}
+
+// Sort keys
+
+// Sorts by field in ascending order
+func Asc(field string) bson.E {
+	return bson.E{field, 1}
+}
+
+// Sorts by field in descending order
+func Desc(field string) bson.E {
+	return bson.E{field, -1}
+}
+
+// Sorts by a metadata value, such as "textScore"
+func SortMeta(field, meta string) bson.E {
+	return bson.E{field, Meta(meta)}
+}
+
+// Projection fields
+
+// Includes field in the output documents
+func Include(field string) bson.E {
+	return bson.E{field, 1}
+}
+
+// Excludes field from the output documents
+func Exclude(field string) bson.E {
+	return bson.E{field, 0}
+}
+
+// Sets field to the value of an expression
+func Computed(field string, expr interface{}) bson.E {
+	return bson.E{field, expr}
+}
+
+// Accumulators and expressions
+
+// Names the output of an accumulator in a $group stage
+func As(field string, accumulator bson.D) bson.E {
+	return bson.E{field, accumulator}
+}
+
+// Returns a $sum accumulator
+func Sum(expr interface{}) bson.D { return bson.D{{"$sum", expr}} }
+
+// Returns an $avg accumulator
+func Avg(expr interface{}) bson.D { return bson.D{{"$avg", expr}} }
+
+// Returns a $min accumulator
+func Min(expr interface{}) bson.D { return bson.D{{"$min", expr}} }
+
+// Returns a $max accumulator
+func Max(expr interface{}) bson.D { return bson.D{{"$max", expr}} }
+
+// Returns a $first accumulator
+func First(expr interface{}) bson.D { return bson.D{{"$first", expr}} }
+
+// Returns a $push accumulator
+func Push(expr interface{}) bson.D { return bson.D{{"$push", expr}} }
+
+// Counts the documents in each group
+func Count() bson.D {
+	return Sum(1)
+}
+
+// Returns a $meta expression for a metadata value, such as "textScore"
+func Meta(name string) bson.D {
+	return bson.D{{"$meta", name}}
+}
+
+// Matches documents that contain search in a text-indexed field
+func Text(search string) bson.D {
+	return bson.D{{"$text", bson.D{{"$search", search}}}}
+}
@@ -0,0 +1,192 @@
+package aggbuilder
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+)
+
+// Pairs a pipeline built with the helpers with the same pipeline written as
+// raw bson.D values and the Extended JSON that both must produce. The first
+// four cases are the pipelines in aggregation.go and CRUD/textSearch.go.
+type goldenCase struct {
+	name   string
+	built  mongo.Pipeline
+	raw    mongo.Pipeline
+	golden string
+}
+
+func goldenCases() []goldenCase {
+	return []goldenCase{
+		{
+			name: "average",
+			built: Pipeline(
+				Group(Field("category"),
+					As("average_price", Avg(Field("price"))),
+					As("type_total", Count()),
+				),
+			),
+			raw: mongo.Pipeline{
+				bson.D{
+					{"$group", bson.D{
+						{"_id", "$category"},
+						{"average_price", bson.D{{"$avg", "$price"}}},
+						{"type_total", bson.D{{"$sum", 1}}},
+					}}},
+			},
+			golden: `[{"$group":{"_id":"$category","average_price":{"$avg":"$price"},"type_total":{"$sum":1}}}]`,
+		},
+		{
+			name: "unset",
+			built: Pipeline(
+				Match(bson.D{{"toppings", "milk foam"}}),
+				Unset("_id", "category"),
+				Sort(Asc("price"), Asc("toppings")),
+				Limit(2),
+			),
+			raw: mongo.Pipeline{
+				bson.D{{"$match", bson.D{{"toppings", "milk foam"}}}},
+				bson.D{{"$unset", bson.A{"_id", "category"}}},
+				bson.D{{"$sort", bson.D{{"price", 1}, {"toppings", 1}}}},
+				bson.D{{"$limit", 2}},
+			},
+			golden: `[{"$match":{"toppings":"milk foam"}},{"$unset":["_id","category"]},{"$sort":{"price":1,"toppings":1}},{"$limit":2}]`,
+		},
+		{
+			name: "text search",
+			built: Pipeline(
+				Match(Text("herb")),
+			),
+			raw: mongo.Pipeline{
+				bson.D{{"$match", bson.D{{"$text", bson.D{{"$search", "herb"}}}}}},
+			},
+			golden: `[{"$match":{"$text":{"$search":"herb"}}}]`,
+		},
+		{
+			name: "text score",
+			built: Pipeline(
+				Match(Text("vegetarian")),
+				Sort(SortMeta("score", "textScore")),
+				Project(Include("name"), Computed("score", Meta("textScore")), Exclude("_id")),
+			),
+			raw: mongo.Pipeline{
+				bson.D{{"$match", bson.D{{"$text", bson.D{{"$search", "vegetarian"}}}}}},
+				bson.D{{"$sort", bson.D{{"score", bson.D{{"$meta", "textScore"}}}}}},
+				bson.D{{"$project", bson.D{{"name", 1}, {"score", bson.D{{"$meta", "textScore"}}}, {"_id", 0}}}},
+			},
+			golden: `[{"$match":{"$text":{"$search":"vegetarian"}}},{"$sort":{"score":{"$meta":"textScore"}}},{"$project":{"name":1,"score":{"$meta":"textScore"},"_id":0}}]`,
+		},
+		{
+			name: "lookup and unwind",
+			built: Pipeline(
+				Lookup("orders", "_id", "customer_id", "orders"),
+				Unwind("orders"),
+				Group(Field("city"), As("revenue", Sum(Field("orders.total")))),
+				Sort(Desc("revenue")),
+				Skip(1),
+			),
+			raw: mongo.Pipeline{
+				bson.D{{"$lookup", bson.D{{"from", "orders"}, {"localField", "_id"}, {"foreignField", "customer_id"}, {"as", "orders"}}}},
+				bson.D{{"$unwind", "$orders"}},
+				bson.D{{"$group", bson.D{{"_id", "$city"}, {"revenue", bson.D{{"$sum", "$orders.total"}}}}}},
+				bson.D{{"$sort", bson.D{{"revenue", -1}}}},
+				bson.D{{"$skip", 1}},
+			},
+			golden: `[{"$lookup":{"from":"orders","localField":"_id","foreignField":"customer_id","as":"orders"}},{"$unwind":"$orders"},{"$group":{"_id":"$city","revenue":{"$sum":"$orders.total"}}},{"$sort":{"revenue":-1}},{"$skip":1}]`,
+		},
+		{
+			name: "lookup with a pipeline",
+			built: Pipeline(
+				LookupPipeline("orders",
+					bson.D{{"customerId", "$_id"}},
+					Pipeline(
+						Match(bson.D{{"$expr", bson.D{{"$eq", bson.A{"$customer_id", "$$customerId"}}}}}),
+						Project(Include("total")),
+					),
+					"orders"),
+			),
+			raw: mongo.Pipeline{
+				bson.D{{"$lookup", bson.D{
+					{"from", "orders"},
+					{"let", bson.D{{"customerId", "$_id"}}},
+					{"pipeline", mongo.Pipeline{
+						bson.D{{"$match", bson.D{{"$expr", bson.D{{"$eq", bson.A{"$customer_id", "$$customerId"}}}}}}},
+						bson.D{{"$project", bson.D{{"total", 1}}}},
+					}},
+					{"as", "orders"},
+				}}},
+			},
+			golden: `[{"$lookup":{"from":"orders","let":{"customerId":"$_id"},"pipeline":[{"$match":{"$expr":{"$eq":["$customer_id","$$customerId"]}}},{"$project":{"total":1}}],"as":"orders"}}]`,
+		},
+		{
+			name: "accumulators",
+			built: Pipeline(
+				Group(nil,
+					As("min", Min(Field("price"))),
+					As("max", Max(Field("price"))),
+					As("first", First(Field("type"))),
+					As("types", Push(Field("type"))),
+				),
+			),
+			raw: mongo.Pipeline{
+				bson.D{{"$group", bson.D{
+					{"_id", nil},
+					{"min", bson.D{{"$min", "$price"}}},
+					{"max", bson.D{{"$max", "$price"}}},
+					{"first", bson.D{{"$first", "$type"}}},
+					{"types", bson.D{{"$push", "$type"}}},
+				}}},
+			},
+			golden: `[{"$group":{"_id":null,"min":{"$min":"$price"},"max":{"$max":"$price"},"first":{"$first":"$type"},"types":{"$push":"$type"}}}]`,
+		},
+	}
+}
+
+// Marshals each stage to raw BSON
+func encode(t *testing.T, pipeline mongo.Pipeline) []bson.Raw {
+	t.Helper()
+	stages := make([]bson.Raw, len(pipeline))
+	for i, stage := range pipeline {
+		b, err := bson.Marshal(stage)
+		if err != nil {
+			t.Fatal(err)
+		}
+		stages[i] = b
+	}
+	return stages
+}
+
+// Returns the relaxed Extended JSON form of the encoded stages
+func extJSON(t *testing.T, stages []bson.Raw) string {
+	t.Helper()
+	parts := make([]string, len(stages))
+	for i, stage := range stages {
+		b, err := bson.MarshalExtJSON(stage, false, false)
+		if err != nil {
+			t.Fatal(err)
+		}
+		parts[i] = string(b)
+	}
+	return "[" + strings.Join(parts, ",") + "]"
+}
+
+// Checks that each built pipeline encodes to the same BSON as its raw form
+// and to the golden Extended JSON
+func TestGolden(t *testing.T) {
+	for _, c := range goldenCases() {
+		t.Run(c.name, func(t *testing.T) {
+			built := encode(t, c.built)
+			raw := encode(t, c.raw)
+			if !reflect.DeepEqual(built, raw) {
+				t.Fatalf("built pipeline differs from raw pipeline\nbuilt: %s\nraw:   %s",
+					extJSON(t, built), extJSON(t, raw))
+			}
+			if got := extJSON(t, built); got != c.golden {
+				t.Errorf("built pipeline differs from golden output\ngot:  %s\nwant: %s", got, c.golden)
+			}
+		})
+	}
+}
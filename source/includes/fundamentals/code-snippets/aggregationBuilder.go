@@ -0,0 +1,162 @@
+// Builds the pipelines from aggregation.go and textSearch.go with the
+// aggbuilder package by using the Go driver
+//
+// Run aggregation.go and textSearch.go first to create the "tea" and "menu"
+// collections that this program reads.
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+
+	"github.com/mongodb/docs-golang/source/includes/fundamentals/code-snippets/aggbuilder"
+)
+
+type Tea struct {
+	Type     string
+	Category string
+	Toppings []string
+	Price    float32
+}
+
+type Dish struct {
+	Name        string
+	Description string
+}
+
+func main() {
+	var uri string
+	if uri = os.Getenv("MONGODB_URI"); uri == "" {
+		log.Fatal("You must set your 'MONGODB_URI' environment variable. See\n\t https://www.mongodb.com/docs/drivers/go/current/usage-examples/")
+	}
+
+	client, err := mongo.Connect(options.Client().ApplyURI(uri))
+	if err != nil {
+		panic(err)
+	}
+	defer func() {
+		if err = client.Disconnect(context.TODO()); err != nil {
+			panic(err)
+		}
+	}()
+
+	teaColl := client.Database("db").Collection("tea")
+	menuColl := client.Database("db").Collection("menu")
+
+	fmt.Println("Builder Example - Average:")
+	{
+		// Groups documents by "category" and calculates the average price
+		// and total number of documents for each "category"
+		// begin builder average
+		pipeline := aggbuilder.Pipeline(
+			aggbuilder.Group(aggbuilder.Field("category"),
+				aggbuilder.As("average_price", aggbuilder.Avg(aggbuilder.Field("price"))),
+				aggbuilder.As("type_total", aggbuilder.Count()),
+			),
+		)
+
+		cursor, err := teaColl.Aggregate(context.TODO(), pipeline)
+		if err != nil {
+			panic(err)
+		}
+
+		var results []bson.M
+		if err = cursor.All(context.TODO(), &results); err != nil {
+			panic(err)
+		}
+		for _, result := range results {
+			fmt.Printf("Average price of %v tea options: $%v \n", result["_id"], result["average_price"])
+			fmt.Printf("Number of %v tea options: %v \n\n", result["_id"], result["type_total"])
+		}
+		// end builder average
+	}
+
+	fmt.Println("Builder Example - Unset:")
+	{
+		// Matches documents, removes the "_id" and "category" fields,
+		// specifies a sort, and limits the output to 2 documents
+		// begin builder unset
+		pipeline := aggbuilder.Pipeline(
+			aggbuilder.Match(bson.D{{"toppings", "milk foam"}}),
+			aggbuilder.Unset("_id", "category"),
+			aggbuilder.Sort(aggbuilder.Asc("price"), aggbuilder.Asc("toppings")),
+			aggbuilder.Limit(2),
+		)
+
+		cursor, err := teaColl.Aggregate(context.TODO(), pipeline)
+		if err != nil {
+			panic(err)
+		}
+
+		var results []Tea
+		if err = cursor.All(context.TODO(), &results); err != nil {
+			panic(err)
+		}
+		for _, result := range results {
+			fmt.Printf("Tea: %v \nToppings: %v \nPrice: $%v \n\n", result.Type, strings.Join(result.Toppings, ", "), result.Price)
+		}
+		// end builder unset
+	}
+
+	fmt.Println("Builder Example - Text Search:")
+	{
+		// Retrieves documents containing the "herb" string in any fields
+		// associated with a text index
+		// begin builder text search
+		pipeline := aggbuilder.Pipeline(
+			aggbuilder.Match(aggbuilder.Text("herb")),
+		)
+
+		cursor, err := menuColl.Aggregate(context.TODO(), pipeline)
+		if err != nil {
+			panic(err)
+		}
+
+		var results []Dish
+		if err = cursor.All(context.TODO(), &results); err != nil {
+			panic(err)
+		}
+		for _, result := range results {
+			res, _ := bson.MarshalExtJSON(result, false, false)
+			fmt.Println(string(res))
+		}
+		// end builder text search
+	}
+
+	fmt.Println("\nBuilder Example - Text Score:")
+	{
+		// Retrieves documents containing the "vegetarian" string and
+		// sorts the results by relevance based on the "textScore" field
+		// begin builder text score
+		pipeline := aggbuilder.Pipeline(
+			aggbuilder.Match(aggbuilder.Text("vegetarian")),
+			aggbuilder.Sort(aggbuilder.SortMeta("score", "textScore")),
+			aggbuilder.Project(
+				aggbuilder.Include("name"),
+				aggbuilder.Computed("score", aggbuilder.Meta("textScore")),
+				aggbuilder.Exclude("_id"),
+			),
+		)
+
+		cursor, err := menuColl.Aggregate(context.TODO(), pipeline)
+		if err != nil {
+			panic(err)
+		}
+
+		var results []bson.D
+		if err = cursor.All(context.TODO(), &results); err != nil {
+			panic(err)
+		}
+		for _, result := range results {
+			fmt.Println(result)
+		}
+		// end builder text score
+	}
+}
@@ -0,0 +1,367 @@
+// Joins, reshapes, and groups related collections with aggregation stages by
+// using the Go driver
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+)
+
+// start-structs
+type Customer struct {
+	ID   int32  `bson:"_id"`
+	Name string `bson:"name"`
+	City string `bson:"city"`
+}
+
+type Order struct {
+	ID         int32  `bson:"_id"`
+	CustomerID int32  `bson:"customer_id"`
+	Category   string `bson:"category"`
+	Total      int32  `bson:"total"`
+	Status     string `bson:"status"`
+}
+
+type Category struct {
+	ID     string `bson:"_id"`
+	Parent string `bson:"parent,omitempty"`
+}
+
+// end-structs
+
+// start-result-structs
+type OrderSummary struct {
+	ID    int32 `bson:"_id"`
+	Total int32 `bson:"total"`
+}
+
+type CustomerOrders struct {
+	Name   string         `bson:"name"`
+	Orders []OrderSummary `bson:"orders"`
+}
+
+type CityRevenue struct {
+	City    string `bson:"_id"`
+	Revenue int32  `bson:"revenue"`
+	Orders  int32  `bson:"orders"`
+}
+
+type FacetCount struct {
+	Value interface{} `bson:"_id"`
+	Count int32       `bson:"count"`
+}
+
+type OrderFacets struct {
+	Results     []Order      `bson:"results"`
+	Categories  []FacetCount `bson:"categories"`
+	Statuses    []FacetCount `bson:"statuses"`
+	PriceRanges []FacetCount `bson:"priceRanges"`
+}
+
+type PriceBucket struct {
+	Range struct {
+		Min int32 `bson:"min"`
+		Max int32 `bson:"max"`
+	} `bson:"_id"`
+	Count   int32   `bson:"count"`
+	Average float64 `bson:"average"`
+}
+
+type RelatedCategory struct {
+	ID    string `bson:"_id"`
+	Depth int64  `bson:"depth"`
+}
+
+type CategoryTree struct {
+	ID          string            `bson:"_id"`
+	Ancestors   []RelatedCategory `bson:"ancestors"`
+	Descendants []RelatedCategory `bson:"descendants"`
+}
+
+// end-result-structs
+
+func main() {
+	var uri string
+	if uri = os.Getenv("MONGODB_URI"); uri == "" {
+		log.Fatal("You must set your 'MONGODB_URI' environment variable. See\n\t https://www.mongodb.com/docs/drivers/go/current/usage-examples/")
+	}
+
+	client, err := mongo.Connect(options.Client().ApplyURI(uri))
+	if err != nil {
+		panic(err)
+	}
+	defer func() {
+		if err = client.Disconnect(context.TODO()); err != nil {
+			panic(err)
+		}
+	}()
+
+	db := client.Database("db")
+	insertDocs(db)
+	customerColl := db.Collection("customers")
+	orderColl := db.Collection("orders")
+	categoryColl := db.Collection("categories")
+
+	fmt.Println("\n$lookup With a Pipeline:")
+	lookupShippedOrders(customerColl)
+
+	fmt.Println("\n$unwind:")
+	revenueByCity(customerColl)
+
+	fmt.Println("\n$facet:")
+	orderFacets(orderColl)
+
+	fmt.Println("\n$bucketAuto:")
+	priceBuckets(orderColl)
+
+	fmt.Println("\n$graphLookup:")
+	categoryTrees(categoryColl)
+}
+
+// Replaces the customers, orders, and categories collections in db
+func insertDocs(db *mongo.Database) {
+	// Inserts sample documents describing customers, their orders, and a
+	// tree of product categories
+	// begin insert docs
+	customerColl := db.Collection("customers")
+	orderColl := db.Collection("orders")
+	categoryColl := db.Collection("categories")
+
+	customers := []interface{}{
+		Customer{ID: 1, Name: "Ana", City: "Lisbon"},
+		Customer{ID: 2, Name: "Ben", City: "Oslo"},
+		Customer{ID: 3, Name: "Chi", City: "Lisbon"},
+	}
+	orders := []interface{}{
+		Order{ID: 101, CustomerID: 1, Category: "laptops", Total: 1200, Status: "shipped"},
+		Order{ID: 102, CustomerID: 1, Category: "phones", Total: 650, Status: "pending"},
+		Order{ID: 103, CustomerID: 2, Category: "cookware", Total: 80, Status: "shipped"},
+		Order{ID: 104, CustomerID: 2, Category: "laptops", Total: 1800, Status: "shipped"},
+		Order{ID: 105, CustomerID: 3, Category: "phones", Total: 300, Status: "shipped"},
+		Order{ID: 106, CustomerID: 3, Category: "cookware", Total: 45, Status: "cancelled"},
+		Order{ID: 107, CustomerID: 1, Category: "cookware", Total: 120, Status: "shipped"},
+	}
+	categories := []interface{}{
+		Category{ID: "electronics"},
+		Category{ID: "computers", Parent: "electronics"},
+		Category{ID: "laptops", Parent: "computers"},
+		Category{ID: "phones", Parent: "electronics"},
+		Category{ID: "kitchen"},
+		Category{ID: "cookware", Parent: "kitchen"},
+	}
+
+	for coll, docs := range map[*mongo.Collection][]interface{}{
+		customerColl: customers,
+		orderColl:    orders,
+		categoryColl: categories,
+	} {
+		if err := coll.Drop(context.TODO()); err != nil {
+			panic(err)
+		}
+		if _, err := coll.InsertMany(context.TODO(), docs); err != nil {
+			panic(err)
+		}
+	}
+	// end insert docs
+}
+
+// Runs the $lookup example and returns its results
+func lookupShippedOrders(customerColl *mongo.Collection) []CustomerOrders {
+	// Joins each customer to their shipped orders, largest first
+	// begin lookup pipeline
+	lookupStage := bson.D{{"$lookup", bson.D{
+		{"from", "orders"},
+		{"let", bson.D{{"customerId", "$_id"}}},
+		{"pipeline", bson.A{
+			bson.D{{"$match", bson.D{{"$expr", bson.D{{"$and", bson.A{
+				bson.D{{"$eq", bson.A{"$customer_id", "$$customerId"}}},
+				bson.D{{"$eq", bson.A{"$status", "shipped"}}},
+			}}}}}}},
+			bson.D{{"$sort", bson.D{{"total", -1}}}},
+			bson.D{{"$project", bson.D{{"_id", 1}, {"total", 1}}}},
+		}},
+		{"as", "orders"},
+	}}}
+	sortStage := bson.D{{"$sort", bson.D{{"_id", 1}}}}
+
+	cursor, err := customerColl.Aggregate(context.TODO(), mongo.Pipeline{lookupStage, sortStage})
+	if err != nil {
+		panic(err)
+	}
+
+	var results []CustomerOrders
+	if err = cursor.All(context.TODO(), &results); err != nil {
+		panic(err)
+	}
+	for _, result := range results {
+		fmt.Printf("%s: %+v\n", result.Name, result.Orders)
+	}
+	// end lookup pipeline
+
+	return results
+}
+
+// Runs the $unwind example and returns its results
+func revenueByCity(customerColl *mongo.Collection) []CityRevenue {
+	// Joins customers to their orders, outputs one document for each
+	// order, and totals shipped revenue by city
+	// begin unwind
+	lookupStage := bson.D{{"$lookup", bson.D{
+		{"from", "orders"},
+		{"localField", "_id"},
+		{"foreignField", "customer_id"},
+		{"as", "orders"},
+	}}}
+	unwindStage := bson.D{{"$unwind", "$orders"}}
+	matchStage := bson.D{{"$match", bson.D{{"orders.status", "shipped"}}}}
+	groupStage := bson.D{{"$group", bson.D{
+		{"_id", "$city"},
+		{"revenue", bson.D{{"$sum", "$orders.total"}}},
+		{"orders", bson.D{{"$sum", 1}}},
+	}}}
+	sortStage := bson.D{{"$sort", bson.D{{"_id", 1}}}}
+
+	cursor, err := customerColl.Aggregate(context.TODO(),
+		mongo.Pipeline{lookupStage, unwindStage, matchStage, groupStage, sortStage})
+	if err != nil {
+		panic(err)
+	}
+
+	var results []CityRevenue
+	if err = cursor.All(context.TODO(), &results); err != nil {
+		panic(err)
+	}
+	for _, result := range results {
+		fmt.Printf("%s: %d shipped orders, revenue %d\n", result.City, result.Orders, result.Revenue)
+	}
+	// end unwind
+
+	return results
+}
+
+// Runs the $facet example and returns its results
+func orderFacets(orderColl *mongo.Collection) OrderFacets {
+	// Returns the top search results together with counts by category,
+	// counts by status, and counts by price range in one query
+	// begin facet
+	matchStage := bson.D{{"$match", bson.D{{"status", bson.D{{"$ne", "cancelled"}}}}}}
+	facetStage := bson.D{{"$facet", bson.D{
+		{"results", bson.A{
+			bson.D{{"$sort", bson.D{{"total", -1}}}},
+			bson.D{{"$limit", 3}},
+		}},
+		{"categories", bson.A{
+			bson.D{{"$sortByCount", "$category"}},
+		}},
+		{"statuses", bson.A{
+			bson.D{{"$sortByCount", "$status"}},
+		}},
+		{"priceRanges", bson.A{
+			bson.D{{"$bucket", bson.D{
+				{"groupBy", "$total"},
+				{"boundaries", bson.A{0, 100, 500, 1000, 5000}},
+				{"default", "other"},
+			}}},
+		}},
+	}}}
+
+	cursor, err := orderColl.Aggregate(context.TODO(), mongo.Pipeline{matchStage, facetStage})
+	if err != nil {
+		panic(err)
+	}
+
+	var results []OrderFacets
+	if err = cursor.All(context.TODO(), &results); err != nil {
+		panic(err)
+	}
+	facets := results[0]
+	for _, order := range facets.Results {
+		fmt.Printf("Order %d: %s, %d\n", order.ID, order.Category, order.Total)
+	}
+	fmt.Printf("Categories: %v\n", facets.Categories)
+	fmt.Printf("Statuses: %v\n", facets.Statuses)
+	fmt.Printf("Price ranges: %v\n", facets.PriceRanges)
+	// end facet
+
+	return facets
+}
+
+// Runs the $bucketAuto example and returns its results
+func priceBuckets(orderColl *mongo.Collection) []PriceBucket {
+	// Divides orders into three price ranges that hold a similar number
+	// of orders each
+	// begin bucket auto
+	bucketAutoStage := bson.D{{"$bucketAuto", bson.D{
+		{"groupBy", "$total"},
+		{"buckets", 3},
+		{"output", bson.D{
+			{"count", bson.D{{"$sum", 1}}},
+			{"average", bson.D{{"$avg", "$total"}}},
+		}},
+	}}}
+
+	cursor, err := orderColl.Aggregate(context.TODO(), mongo.Pipeline{bucketAutoStage})
+	if err != nil {
+		panic(err)
+	}
+
+	var results []PriceBucket
+	if err = cursor.All(context.TODO(), &results); err != nil {
+		panic(err)
+	}
+	for _, result := range results {
+		fmt.Printf("%d to %d: %d orders, average %.2f\n",
+			result.Range.Min, result.Range.Max, result.Count, result.Average)
+	}
+	// end bucket auto
+
+	return results
+}
+
+// Runs the $graphLookup example and returns its results
+func categoryTrees(categoryColl *mongo.Collection) []CategoryTree {
+	// Finds every ancestor of the "laptops" category and every
+	// descendant of the "electronics" category
+	// begin graph lookup
+	matchStage := bson.D{{"$match", bson.D{{"_id", bson.D{{"$in", bson.A{"laptops", "electronics"}}}}}}}
+	ancestorsStage := bson.D{{"$graphLookup", bson.D{
+		{"from", "categories"},
+		{"startWith", "$parent"},
+		{"connectFromField", "parent"},
+		{"connectToField", "_id"},
+		{"as", "ancestors"},
+		{"depthField", "depth"},
+	}}}
+	descendantsStage := bson.D{{"$graphLookup", bson.D{
+		{"from", "categories"},
+		{"startWith", "$_id"},
+		{"connectFromField", "_id"},
+		{"connectToField", "parent"},
+		{"as", "descendants"},
+		{"depthField", "depth"},
+	}}}
+	sortStage := bson.D{{"$sort", bson.D{{"_id", 1}}}}
+
+	cursor, err := categoryColl.Aggregate(context.TODO(),
+		mongo.Pipeline{matchStage, ancestorsStage, descendantsStage, sortStage})
+	if err != nil {
+		panic(err)
+	}
+
+	var results []CategoryTree
+	if err = cursor.All(context.TODO(), &results); err != nil {
+		panic(err)
+	}
+	for _, result := range results {
+		fmt.Printf("%s: ancestors %v, descendants %v\n", result.ID, result.Ancestors, result.Descendants)
+	}
+	// end graph lookup
+
+	return results
+}
@@ -0,0 +1,155 @@
+// Tests the aggregation stage examples against a deployment. Run with:
+//
+//	MONGODB_URI=<connection string> go test -vet=off aggregationStages.go aggregationStages_test.go
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"reflect"
+	"sort"
+	"testing"
+
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+)
+
+// Inserts the sample documents and returns the database that holds them
+func sampleDatabase(t *testing.T) *mongo.Database {
+	t.Helper()
+	uri := os.Getenv("MONGODB_URI")
+	if uri == "" {
+		t.Skip("set MONGODB_URI to run the aggregation stage examples")
+	}
+
+	client, err := mongo.Connect(options.Client().ApplyURI(uri))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { client.Disconnect(context.Background()) })
+
+	db := client.Database("db")
+	insertDocs(db)
+	return db
+}
+
+func TestLookupShippedOrders(t *testing.T) {
+	db := sampleDatabase(t)
+
+	got := lookupShippedOrders(db.Collection("customers"))
+	want := []CustomerOrders{
+		{Name: "Ana", Orders: []OrderSummary{{101, 1200}, {107, 120}}},
+		{Name: "Ben", Orders: []OrderSummary{{104, 1800}, {103, 80}}},
+		{Name: "Chi", Orders: []OrderSummary{{105, 300}}},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %+v, want %+v", got, want)
+	}
+}
+
+func TestRevenueByCity(t *testing.T) {
+	db := sampleDatabase(t)
+
+	got := revenueByCity(db.Collection("customers"))
+	want := []CityRevenue{
+		{City: "Lisbon", Revenue: 1620, Orders: 3},
+		{City: "Oslo", Revenue: 1880, Orders: 2},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %+v, want %+v", got, want)
+	}
+}
+
+func TestOrderFacets(t *testing.T) {
+	db := sampleDatabase(t)
+	facets := orderFacets(db.Collection("orders"))
+
+	var ids []int32
+	for _, order := range facets.Results {
+		ids = append(ids, order.ID)
+	}
+	if want := []int32{104, 101, 102}; !reflect.DeepEqual(ids, want) {
+		t.Errorf("results: got %v, want %v", ids, want)
+	}
+
+	cases := []struct {
+		name   string
+		counts []FacetCount
+		want   map[string]int32
+	}{
+		{"categories", facets.Categories, map[string]int32{"laptops": 2, "phones": 2, "cookware": 2}},
+		{"statuses", facets.Statuses, map[string]int32{"shipped": 5, "pending": 1}},
+		{"price ranges", facets.PriceRanges, map[string]int32{"0": 1, "100": 2, "500": 1, "1000": 2}},
+	}
+	for _, c := range cases {
+		if got := countsByValue(c.counts); !reflect.DeepEqual(got, c.want) {
+			t.Errorf("%s: got %v, want %v", c.name, got, c.want)
+		}
+	}
+}
+
+func TestPriceBuckets(t *testing.T) {
+	db := sampleDatabase(t)
+
+	results := priceBuckets(db.Collection("orders"))
+	if len(results) != 3 {
+		t.Fatalf("got %d buckets, want 3", len(results))
+	}
+	var total int32
+	for _, result := range results {
+		total += result.Count
+	}
+	if total != 7 {
+		t.Errorf("buckets hold %d orders, want 7", total)
+	}
+}
+
+func TestCategoryTrees(t *testing.T) {
+	db := sampleDatabase(t)
+
+	got := categoryTrees(db.Collection("categories"))
+	// $graphLookup does not guarantee the order of its output
+	for i := range got {
+		got[i].Ancestors = sortByDepth(got[i].Ancestors)
+		got[i].Descendants = sortByDepth(got[i].Descendants)
+	}
+	want := []CategoryTree{
+		{
+			ID:          "electronics",
+			Descendants: []RelatedCategory{{"computers", 0}, {"phones", 0}, {"laptops", 1}},
+		},
+		{
+			ID:        "laptops",
+			Ancestors: []RelatedCategory{{"computers", 0}, {"electronics", 1}},
+		},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %+v, want %+v", got, want)
+	}
+}
+
+// Converts facet counts to a map so that they can be compared regardless of
+// the order of counts that are equal
+func countsByValue(counts []FacetCount) map[string]int32 {
+	m := map[string]int32{}
+	for _, c := range counts {
+		m[fmt.Sprint(c.Value)] = c.Count
+	}
+	return m
+}
+
+// Sorts related categories by depth, then by name. Returns nil for an empty
+// slice so that results compare equal however the driver decodes them.
+func sortByDepth(s []RelatedCategory) []RelatedCategory {
+	if len(s) == 0 {
+		return nil
+	}
+	sort.Slice(s, func(i, j int) bool {
+		if s[i].Depth != s[j].Depth {
+			return s[i].Depth < s[j].Depth
+		}
+		return s[i].ID < s[j].ID
+	})
+	return s
+}
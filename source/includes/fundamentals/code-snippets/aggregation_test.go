@@ -0,0 +1,39 @@
+// Tests the average and unset aggregation examples against the fake server
+// in tools/fakeserver. The headings that main prints fail the printf check
+// of go vet, so run with:
+//
+//	go test -vet=off aggregation.go aggregation_test.go
+package main
+
+import (
+	"testing"
+
+	"github.com/mongodb/docs-golang/source/includes/fundamentals/code-snippets/tools/snippettest"
+)
+
+// Output of main against the fake server
+const golden = `Number of documents inserted: 8
+
+Aggregation Example - Average
+
+Average price of black tea options: $6.075000047683716
+Number of black tea options: 4
+
+Average price of green tea options: $5.700000047683716
+Number of green tea options: 4
+
+
+Aggregation Example - Unset
+
+Tea: Hojicha
+Toppings: lemon, ginger, milk foam
+Price: $5.55
+
+Tea: Gyokuro
+Toppings: berries, milk foam
+Price: $5.65
+`
+
+func TestAggregation(t *testing.T) {
+	snippettest.Equal(t, snippettest.Run(t, main), golden)
+}
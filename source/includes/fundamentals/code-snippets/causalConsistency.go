@@ -0,0 +1,225 @@
+// Reads your own writes from a secondary by using a causally consistent
+// session with the Go driver
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"sync"
+	"time"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+	"go.mongodb.org/mongo-driver/v2/mongo/readconcern"
+	"go.mongodb.org/mongo-driver/v2/mongo/readpref"
+	"go.mongodb.org/mongo-driver/v2/mongo/writeconcern"
+)
+
+// start-order-struct
+type Order struct {
+	ID       bson.ObjectID `bson:"_id"`
+	Item     string
+	Quantity int32
+}
+
+// end-order-struct
+
+func main() {
+	var uri string
+	if uri = os.Getenv("MONGODB_URI"); uri == "" {
+		log.Fatal("You must set your 'MONGODB_URI' environment variable. See\n\t https://www.mongodb.com/docs/drivers/go/current/usage-examples/")
+	}
+
+	client, err := mongo.Connect(options.Client().ApplyURI(uri))
+	if err != nil {
+		panic(err)
+	}
+	defer func() {
+		if err = client.Disconnect(context.TODO()); err != nil {
+			panic(err)
+		}
+	}()
+
+	// Optionally pauses replication to one secondary so that reads from
+	// it lag behind the primary. Set SECONDARY_URI to a connection string
+	// for a secondary started with "enableTestCommands=1". The
+	// deferred call resumes replication if main returns or panics before
+	// the timer fires.
+	secondaryURI := os.Getenv("SECONDARY_URI")
+	if secondaryURI != "" {
+		resume := lagSecondary(secondaryURI)
+		defer resume()
+		time.AfterFunc(2*time.Second, resume)
+	}
+
+	// Connects a second client in another goroutine. The second client
+	// connects directly to the lagged secondary, if there is one, so that
+	// its read must wait for replication to resume.
+	readURI, direct := uri, false
+	if secondaryURI != "" {
+		readURI, direct = secondaryURI, true
+	}
+	order := Order{ID: bson.NewObjectID(), Item: "espresso beans", Quantity: 3}
+	times, results := startOtherClient(readURI, direct, order.ID)
+
+	// start-causal-session
+	// Uses majority read and write concerns so that causally consistent
+	// reads observe writes that cannot be rolled back
+	coll := client.Database("db").Collection("orders",
+		options.Collection().
+			SetWriteConcern(writeconcern.Majority()).
+			SetReadConcern(readconcern.Majority()))
+
+	// Starts a causally consistent session
+	sessOpts := options.Session().SetCausalConsistency(true)
+	session, err := client.StartSession(sessOpts)
+	if err != nil {
+		panic(err)
+	}
+	defer session.EndSession(context.TODO())
+
+	ctx := mongo.NewSessionContext(context.TODO(), session)
+
+	// Writes a document on the primary
+	if _, err = coll.InsertOne(ctx, order); err != nil {
+		panic(err)
+	}
+
+	// Reads the document from a secondary in the same session. The driver
+	// sends "afterClusterTime", so the secondary waits until it has applied
+	// the write before it responds. The read preference lets the driver
+	// choose any secondary, which might not be the lagged one.
+	secondaryColl := coll.Database().Collection(coll.Name(),
+		options.Collection().SetReadPreference(readpref.Secondary()))
+
+	var result Order
+	err = secondaryColl.FindOne(ctx, bson.D{{"_id", order.ID}}).Decode(&result)
+	if err != nil {
+		panic(err)
+	}
+	fmt.Printf("Read from secondary in the same session: %+v\n", result)
+	// end-causal-session
+
+	// start-advance-times
+	// Hands the times that the first session has observed to the goroutine
+	// of the second client
+	times <- SessionTimes{
+		ClusterTime:   session.ClusterTime(),
+		OperationTime: session.OperationTime(),
+	}
+	// end-advance-times
+
+	read := <-results
+	if read.Err != nil {
+		panic(read.Err)
+	}
+	fmt.Printf("Read from secondary with a second client: %+v\n", read.Order)
+}
+
+// Times that a causally consistent session has observed
+type SessionTimes struct {
+	ClusterTime   bson.Raw
+	OperationTime *bson.Timestamp
+}
+
+// Result of the read by the second client
+type ReadResult struct {
+	Order Order
+	Err   error
+}
+
+// Starts a goroutine that connects a second client and waits for the first
+// session's times on the returned times channel. The goroutine then reads
+// the document with the given _id in a session advanced to those times and
+// sends the result on the returned results channel. Set direct when uri names
+// a single secondary to read from.
+func startOtherClient(uri string, direct bool, id bson.ObjectID) (chan<- SessionTimes, <-chan ReadResult) {
+	times := make(chan SessionTimes)
+	results := make(chan ReadResult, 1)
+	go func() {
+		order, err := readFromOtherClient(uri, direct, times, id)
+		results <- ReadResult{order, err}
+	}()
+	return times, results
+}
+
+// Reads the document written by the first session from a separate client
+// after advancing a new session to the times received on times
+func readFromOtherClient(uri string, direct bool, times <-chan SessionTimes, id bson.ObjectID) (Order, error) {
+	client, err := mongo.Connect(options.Client().
+		ApplyURI(uri).
+		SetDirect(direct).
+		SetReadPreference(readpref.Secondary()).
+		SetReadConcern(readconcern.Majority()))
+	if err != nil {
+		<-times
+		return Order{}, err
+	}
+	defer client.Disconnect(context.TODO())
+
+	// start-other-client
+	session, err := client.StartSession(options.Session().SetCausalConsistency(true))
+	if err != nil {
+		<-times
+		return Order{}, err
+	}
+	defer session.EndSession(context.TODO())
+
+	// Advances the new session so that its reads happen after the write
+	// from the first session
+	t := <-times
+	if err = session.AdvanceClusterTime(t.ClusterTime); err != nil {
+		return Order{}, err
+	}
+	if err = session.AdvanceOperationTime(t.OperationTime); err != nil {
+		return Order{}, err
+	}
+
+	ctx := mongo.NewSessionContext(context.TODO(), session)
+	coll := client.Database("db").Collection("orders")
+
+	var result Order
+	err = coll.FindOne(ctx, bson.D{{"_id", id}}).Decode(&result)
+	if err == mongo.ErrNoDocuments {
+		return Order{}, errors.New("causal consistency violated: the write is not visible to the second client")
+	}
+	// end-other-client
+	return result, err
+}
+
+// Stops the secondary at secondaryURI from fetching oplog entries and
+// returns a function that resumes replication. The returned function is safe
+// to call more than once.
+func lagSecondary(secondaryURI string) func() {
+	client, err := mongo.Connect(options.Client().ApplyURI(secondaryURI).SetDirect(true))
+	if err != nil {
+		panic(err)
+	}
+
+	setFailPoint := func(mode string) {
+		cmd := bson.D{
+			{"configureFailPoint", "stopReplProducer"},
+			{"mode", mode},
+		}
+		err := client.Database("admin").RunCommand(context.TODO(), cmd).Err()
+		if err != nil {
+			panic(err)
+		}
+	}
+
+	setFailPoint("alwaysOn")
+	fmt.Println("Paused replication to the secondary")
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			setFailPoint("off")
+			fmt.Println("Resumed replication to the secondary")
+			client.Disconnect(context.TODO())
+		})
+	}
+}
@@ -0,0 +1,69 @@
+// Tests the causal consistency example against a local replica set. Run with:
+//
+//	MONGODB_URI=<replica set> SECONDARY_URI=<secondary> go test causalConsistency.go causalConsistency_test.go
+package main
+
+import (
+	"context"
+	"os"
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+	"go.mongodb.org/mongo-driver/v2/mongo/readconcern"
+	"go.mongodb.org/mongo-driver/v2/mongo/writeconcern"
+)
+
+// Writes with replication to one secondary paused and checks that a second
+// client in another goroutine, connected directly to that secondary, waits
+// for the write instead of returning a stale result
+func TestReadYourWritesOnLaggedSecondary(t *testing.T) {
+	uri, secondaryURI := os.Getenv("MONGODB_URI"), os.Getenv("SECONDARY_URI")
+	if uri == "" || secondaryURI == "" {
+		t.Skip("set MONGODB_URI to a replica set and SECONDARY_URI to a direct connection to one of its secondaries started with enableTestCommands=1")
+	}
+
+	client, err := mongo.Connect(options.Client().ApplyURI(uri))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.Disconnect(context.Background())
+
+	coll := client.Database("db").Collection("orders",
+		options.Collection().
+			SetWriteConcern(writeconcern.Majority()).
+			SetReadConcern(readconcern.Majority()))
+
+	session, err := client.StartSession(options.Session().SetCausalConsistency(true))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer session.EndSession(context.Background())
+
+	const lag = time.Second
+	resume := lagSecondary(secondaryURI)
+	defer resume()
+
+	order := Order{ID: bson.NewObjectID(), Item: "espresso beans", Quantity: 3}
+	times, results := startOtherClient(secondaryURI, true, order.ID)
+	if _, err = coll.InsertOne(mongo.NewSessionContext(context.Background(), session), order); err != nil {
+		t.Fatal(err)
+	}
+	defer coll.DeleteOne(context.Background(), bson.D{{"_id", order.ID}})
+
+	start := time.Now()
+	time.AfterFunc(lag, resume)
+	times <- SessionTimes{ClusterTime: session.ClusterTime(), OperationTime: session.OperationTime()}
+	read := <-results
+	if read.Err != nil {
+		t.Fatal(read.Err)
+	}
+	if read.Order != order {
+		t.Errorf("got %+v, want %+v", read.Order, order)
+	}
+	if elapsed := time.Since(start); elapsed < lag {
+		t.Errorf("read returned after %v, before replication resumed after %v", elapsed, lag)
+	}
+}
@@ -0,0 +1,274 @@
+// Stores and aggregates amounts of money as Decimal128 values by using the
+// Go driver
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"math/big"
+	"os"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+)
+
+// start-order-structs
+type LineItem struct {
+	Name      string          `bson:"name"`
+	UnitPrice bson.Decimal128 `bson:"unit_price"`
+	Quantity  int             `bson:"quantity"`
+}
+
+type Order struct {
+	ID       int             `bson:"_id"`
+	Customer string          `bson:"customer"`
+	Items    []LineItem      `bson:"items"`
+	Total    bson.Decimal128 `bson:"total"`
+}
+
+// end-order-structs
+
+func main() {
+	fmt.Println("Floating-Point Drift:")
+	{
+		// Adds ten cents ten times as float64 and as Decimal128 values
+		// begin drift
+		var f float64
+		sum := new(big.Rat)
+		dime := mustParseMoney("0.10")
+		for i := 0; i < 10; i++ {
+			f += 0.10
+			sum.Add(sum, toRat(dime))
+		}
+		fmt.Printf("float64 sum: %v\n", f)
+		fmt.Printf("Decimal128 sum: %v\n", fromRat(sum))
+		// end drift
+	}
+
+	var uri string
+	if uri = os.Getenv("MONGODB_URI"); uri == "" {
+		log.Fatal("You must set your 'MONGODB_URI' environment variable. See\n\t https://www.mongodb.com/docs/drivers/go/current/usage-examples/")
+	}
+
+	client, err := mongo.Connect(options.Client().ApplyURI(uri))
+	if err != nil {
+		panic(err)
+	}
+	defer func() {
+		if err = client.Disconnect(context.TODO()); err != nil {
+			panic(err)
+		}
+	}()
+
+	coll := client.Database("db").Collection("orders")
+	_ = coll.Drop(context.TODO())
+
+	fmt.Println("\nInsert Orders:")
+	{
+		// begin insert orders
+		orders := sampleOrders()
+		docs := make([]interface{}, len(orders))
+		for i := range orders {
+			docs[i] = orders[i]
+		}
+
+		result, err := coll.InsertMany(context.TODO(), docs)
+		if err != nil {
+			panic(err)
+		}
+		fmt.Printf("Number of documents inserted: %d\n", len(result.InsertedIDs))
+		// end insert orders
+	}
+
+	fmt.Println("\nTotals by Customer:")
+	{
+		// begin sum and avg
+		cursor, err := coll.Aggregate(context.TODO(), customerTotalsPipeline())
+		if err != nil {
+			panic(err)
+		}
+
+		var results []CustomerTotal
+		if err = cursor.All(context.TODO(), &results); err != nil {
+			panic(err)
+		}
+		for _, result := range results {
+			fmt.Printf("%s spent $%v, averaging $%v (rounded to $%v)\n",
+				result.Customer, result.Spent, result.AverageOrder, result.AverageOrderRounded)
+		}
+		// end sum and avg
+	}
+
+	fmt.Println("\nLine Item Arithmetic:")
+	{
+		// begin multiply
+		cursor, err := coll.Aggregate(context.TODO(), revenuePipeline())
+		if err != nil {
+			panic(err)
+		}
+
+		var results []struct {
+			Revenue bson.Decimal128 `bson:"revenue"`
+		}
+		if err = cursor.All(context.TODO(), &results); err != nil {
+			panic(err)
+		}
+		fmt.Printf("Revenue: $%v\n", results[0].Revenue)
+		// end multiply
+	}
+}
+
+// start-sample-orders
+// Returns the example orders. Parses prices from strings and computes each
+// order total with exact arithmetic.
+func sampleOrders() []Order {
+	orders := []Order{
+		{ID: 1, Customer: "alice", Items: []LineItem{
+			{Name: "notebook", UnitPrice: mustParseMoney("19.99"), Quantity: 3},
+			{Name: "pencil", UnitPrice: mustParseMoney("0.10"), Quantity: 1},
+		}},
+		{ID: 2, Customer: "alice", Items: []LineItem{
+			{Name: "pencil", UnitPrice: mustParseMoney("0.10"), Quantity: 7},
+			{Name: "eraser", UnitPrice: mustParseMoney("0.20"), Quantity: 2},
+		}},
+		{ID: 3, Customer: "bob", Items: []LineItem{
+			{Name: "desk", UnitPrice: mustParseMoney("1234.56"), Quantity: 1},
+			{Name: "sticker", UnitPrice: mustParseMoney("0.33"), Quantity: 3},
+		}},
+		{ID: 4, Customer: "bob", Items: []LineItem{
+			{Name: "paper clip", UnitPrice: mustParseMoney("0.01"), Quantity: 10},
+		}},
+		{ID: 5, Customer: "carol", Items: []LineItem{
+			{Name: "stapler", UnitPrice: mustParseMoney("9.99"), Quantity: 1},
+		}},
+	}
+	for i := range orders {
+		orders[i].Total = orderTotal(orders[i].Items)
+	}
+	return orders
+}
+
+// end-sample-orders
+
+// start-pipelines
+type CustomerTotal struct {
+	Customer            string          `bson:"_id"`
+	Spent               bson.Decimal128 `bson:"spent"`
+	AverageOrder        bson.Decimal128 `bson:"average_order"`
+	AverageOrderRounded bson.Decimal128 `bson:"average_order_rounded"`
+}
+
+// Sums and averages the Decimal128 totals of each customer. The server
+// performs decimal arithmetic, so the results have no floating-point error.
+func customerTotalsPipeline() mongo.Pipeline {
+	groupStage := bson.D{
+		{"$group", bson.D{
+			{"_id", "$customer"},
+			{"spent", bson.D{{"$sum", "$total"}}},
+			{"average_order", bson.D{{"$avg", "$total"}}},
+		}}}
+	// Rounds the average to whole cents. $round rounds halves to the
+	// nearest even digit.
+	setStage := bson.D{
+		{"$set", bson.D{
+			{"average_order_rounded", bson.D{{"$round", bson.A{"$average_order", 2}}}},
+		}}}
+	sortStage := bson.D{{"$sort", bson.D{{"_id", 1}}}}
+
+	return mongo.Pipeline{groupStage, setStage, sortStage}
+}
+
+// Recomputes the revenue from unit prices and quantities on the server, so
+// that it can be compared with the sum of the stored totals
+func revenuePipeline() mongo.Pipeline {
+	unwindStage := bson.D{{"$unwind", "$items"}}
+	groupStage := bson.D{
+		{"$group", bson.D{
+			{"_id", nil},
+			{"revenue", bson.D{{"$sum", bson.D{
+				{"$multiply", bson.A{"$items.unit_price", "$items.quantity"}},
+			}}}},
+		}}}
+
+	return mongo.Pipeline{unwindStage, groupStage}
+}
+
+// end-pipelines
+
+// start-money-helpers
+// Parses a decimal string such as "19.99" into a Decimal128 value. Rejects
+// NaN and infinite values, which are not amounts of money.
+func parseMoney(s string) (bson.Decimal128, error) {
+	d, err := bson.ParseDecimal128(s)
+	if err != nil {
+		return bson.Decimal128{}, err
+	}
+	if d.IsNaN() || d.IsInf() != 0 {
+		return bson.Decimal128{}, fmt.Errorf("%q is not a finite amount", s)
+	}
+	return d, nil
+}
+
+func mustParseMoney(s string) bson.Decimal128 {
+	d, err := parseMoney(s)
+	if err != nil {
+		panic(err)
+	}
+	return d
+}
+
+// Multiplies each unit price by its quantity and sums the results exactly
+func orderTotal(items []LineItem) bson.Decimal128 {
+	total := new(big.Rat)
+	for _, item := range items {
+		line := new(big.Rat).Mul(toRat(item.UnitPrice), big.NewRat(int64(item.Quantity), 1))
+		total.Add(total, line)
+	}
+	return fromRat(total)
+}
+
+// Converts a finite Decimal128 value to an exact rational number
+func toRat(d bson.Decimal128) *big.Rat {
+	coefficient, exp, err := d.BigInt()
+	if err != nil {
+		panic(err)
+	}
+
+	r := new(big.Rat).SetInt(coefficient)
+	scale := new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(abs(exp))), nil)
+	if exp < 0 {
+		return r.Quo(r, new(big.Rat).SetInt(scale))
+	}
+	return r.Mul(r, new(big.Rat).SetInt(scale))
+}
+
+// Rounds r to whole cents, rounding halves away from zero, and converts the
+// result to a Decimal128 value
+func fromRat(r *big.Rat) bson.Decimal128 {
+	hundred := big.NewInt(100)
+	num := new(big.Int).Mul(r.Num(), hundred)
+	cents, rem := new(big.Int).QuoRem(num, r.Denom(), new(big.Int))
+
+	// Rounds away from zero when the remainder is at least half the
+	// denominator
+	if new(big.Int).Mul(new(big.Int).Abs(rem), big.NewInt(2)).Cmp(r.Denom()) >= 0 {
+		cents.Add(cents, big.NewInt(int64(r.Sign())))
+	}
+
+	d, ok := bson.ParseDecimal128FromBigInt(cents, -2)
+	if !ok {
+		panic(fmt.Sprintf("%v does not fit in a Decimal128 value", r))
+	}
+	return d
+}
+
+// end-money-helpers
+
+func abs(n int) int {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
@@ -0,0 +1,141 @@
+// Tests the money helpers of the Decimal128 example and, when MONGODB_URI is
+// set, the server-side arithmetic. Run with:
+//
+//	MONGODB_URI=<connection string> go test decimalMoney.go decimalMoney_test.go
+package main
+
+import (
+	"context"
+	"math/big"
+	"os"
+	"testing"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+)
+
+// Fails the test if got does not equal the decimal amount in want
+func checkMoney(t *testing.T, name string, got bson.Decimal128, want string) {
+	t.Helper()
+	if toRat(got).Cmp(toRat(mustParseMoney(want))) != 0 {
+		t.Errorf("%s: got %v, want %s", name, got, want)
+	}
+}
+
+func TestParseMoney(t *testing.T) {
+	for _, s := range []string{"19.99", "0.10", "-5", "1E+2"} {
+		if _, err := parseMoney(s); err != nil {
+			t.Errorf("parseMoney(%q): %v", s, err)
+		}
+	}
+	for _, s := range []string{"NaN", "Infinity", "-Infinity", "twelve", ""} {
+		if d, err := parseMoney(s); err == nil {
+			t.Errorf("parseMoney(%q) = %v, want an error", s, d)
+		}
+	}
+}
+
+func TestSumOfDimes(t *testing.T) {
+	sum := new(big.Rat)
+	for i := 0; i < 10; i++ {
+		sum.Add(sum, toRat(mustParseMoney("0.10")))
+	}
+	checkMoney(t, "sum of ten dimes", fromRat(sum), "1.00")
+}
+
+func TestFromRatRounding(t *testing.T) {
+	cases := []struct {
+		num, denom int64
+		want       string
+	}{
+		{1, 3, "0.33"},
+		{2, 3, "0.67"},
+		{1, 200, "0.01"},
+		{-1, 200, "-0.01"},
+		{1, 201, "0.00"},
+		{12345, 1000, "12.35"},
+		{5, 1, "5.00"},
+	}
+	for _, c := range cases {
+		got := fromRat(big.NewRat(c.num, c.denom))
+		if got.String() != c.want {
+			t.Errorf("fromRat(%d/%d) = %v, want %s", c.num, c.denom, got, c.want)
+		}
+	}
+}
+
+func TestOrderTotals(t *testing.T) {
+	want := []string{"60.07", "1.10", "1235.55", "0.10", "9.99"}
+	for i, order := range sampleOrders() {
+		checkMoney(t, "order total", order.Total, want[i])
+	}
+}
+
+// Runs the aggregations against a deployment and checks that the server's
+// decimal arithmetic matches the exact totals
+func TestAggregations(t *testing.T) {
+	uri := os.Getenv("MONGODB_URI")
+	if uri == "" {
+		t.Skip("set MONGODB_URI to run the aggregations")
+	}
+	ctx := context.Background()
+
+	client, err := mongo.Connect(options.Client().ApplyURI(uri))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.Disconnect(ctx)
+
+	coll := client.Database("db").Collection("orders_test")
+	if err = coll.Drop(ctx); err != nil {
+		t.Fatal(err)
+	}
+	defer coll.Drop(ctx)
+
+	var docs []interface{}
+	for _, order := range sampleOrders() {
+		docs = append(docs, order)
+	}
+	if _, err = coll.InsertMany(ctx, docs); err != nil {
+		t.Fatal(err)
+	}
+
+	cursor, err := coll.Aggregate(ctx, customerTotalsPipeline())
+	if err != nil {
+		t.Fatal(err)
+	}
+	var totals []CustomerTotal
+	if err = cursor.All(ctx, &totals); err != nil {
+		t.Fatal(err)
+	}
+	want := map[string][3]string{
+		"alice": {"61.17", "30.585", "30.58"},
+		"bob":   {"1235.65", "617.825", "617.82"},
+		"carol": {"9.99", "9.99", "9.99"},
+	}
+	if len(totals) != len(want) {
+		t.Fatalf("got %d customers, want %d", len(totals), len(want))
+	}
+	for _, total := range totals {
+		w := want[total.Customer]
+		checkMoney(t, total.Customer+" spent", total.Spent, w[0])
+		checkMoney(t, total.Customer+" average", total.AverageOrder, w[1])
+		checkMoney(t, total.Customer+" rounded average", total.AverageOrderRounded, w[2])
+	}
+
+	cursor, err = coll.Aggregate(ctx, revenuePipeline())
+	if err != nil {
+		t.Fatal(err)
+	}
+	var revenue []struct {
+		Revenue bson.Decimal128 `bson:"revenue"`
+	}
+	if err = cursor.All(ctx, &revenue); err != nil {
+		t.Fatal(err)
+	}
+	if len(revenue) != 1 {
+		t.Fatalf("got %d revenue results, want 1", len(revenue))
+	}
+	checkMoney(t, "revenue", revenue[0].Revenue, "1306.81")
+}
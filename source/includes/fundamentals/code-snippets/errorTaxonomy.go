@@ -0,0 +1,154 @@
+// Triggers and classifies the errors that operations can return by using the
+// Go driver
+//
+// This example uses the failCommand fail point, so it must run against a
+// local replica set member started with "--setParameter
+// enableTestCommands=1".
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+)
+
+const appName = "errorTaxonomy"
+
+// start-classify
+// Returns a name for each error category that err belongs to
+func classify(err error) []string {
+	var categories []string
+	add := func(ok bool, name string) {
+		if ok {
+			categories = append(categories, name)
+		}
+	}
+
+	add(errors.Is(err, mongo.ErrNoDocuments), "no documents")
+	add(mongo.IsDuplicateKeyError(err), "duplicate key")
+	add(mongo.IsTimeout(err), "timeout")
+	add(mongo.IsNetworkError(err), "network")
+
+	// Errors from single-document writes such as InsertOne
+	var we mongo.WriteException
+	if errors.As(err, &we) {
+		categories = append(categories, "write exception")
+		add(we.WriteConcernError != nil, "write concern")
+		for _, e := range we.WriteErrors {
+			add(e.Code == 121, "validation")
+		}
+	}
+
+	// Errors from multi-document writes such as InsertMany and BulkWrite
+	var bwe mongo.BulkWriteException
+	if errors.As(err, &bwe) {
+		categories = append(categories, "bulk write exception")
+		add(bwe.WriteConcernError != nil, "write concern")
+		for _, e := range bwe.WriteErrors {
+			add(e.Code == 121, "validation")
+		}
+	}
+
+	var ce mongo.CommandError
+	add(errors.As(err, &ce), "command error")
+
+	// Every error that the server returns implements ServerError
+	var se mongo.ServerError
+	if errors.As(err, &se) {
+		add(se.HasErrorCode(59), "command not found")
+		add(se.HasErrorLabel("TransientTransactionError"), "transient transaction")
+		add(se.HasErrorLabel("RetryableWriteError"), "retryable write")
+	}
+	return categories
+}
+
+// end-classify
+
+// Disables retries so that each fail point affects exactly one attempt
+func clientOptions(uri string) *options.ClientOptions {
+	return options.Client().ApplyURI(uri).
+		SetAppName(appName).
+		SetRetryReads(false).
+		SetRetryWrites(false)
+}
+
+func main() {
+	var uri string
+	if uri = os.Getenv("MONGODB_URI"); uri == "" {
+		log.Fatal("You must set your 'MONGODB_URI' environment variable. See\n\t https://www.mongodb.com/docs/drivers/go/current/usage-examples/")
+	}
+
+	client, err := mongo.Connect(clientOptions(uri))
+	if err != nil {
+		panic(err)
+	}
+	defer func() {
+		if err = client.Disconnect(context.TODO()); err != nil {
+			panic(err)
+		}
+	}()
+
+	coll := client.Database("db").Collection("errors")
+	_ = coll.Drop(context.TODO())
+	if _, err = coll.InsertOne(context.TODO(), bson.D{{"_id", 1}}); err != nil {
+		panic(err)
+	}
+
+	// Triggers errors from the server, from the driver, and from a fail
+	// point, and prints the categories of each
+	// begin classify errors
+	err = coll.FindOne(context.TODO(), bson.D{{"_id", 404}}).Err()
+	fmt.Printf("Find a missing document: %s\n", strings.Join(classify(err), ", "))
+
+	_, err = coll.InsertOne(context.TODO(), bson.D{{"_id", 1}})
+	fmt.Printf("Insert a duplicate _id: %s\n", strings.Join(classify(err), ", "))
+
+	err = client.Database("db").RunCommand(context.TODO(), bson.D{{"notACommand", 1}}).Err()
+	fmt.Printf("Run an unknown command: %s\n", strings.Join(classify(err), ", "))
+
+	disable := setFailPoint(client, bson.D{
+		{"failCommands", bson.A{"find"}},
+		{"errorCode", 112},
+		{"errorLabels", bson.A{"TransientTransactionError"}},
+	})
+	err = coll.FindOne(context.TODO(), bson.D{{"_id", 1}}).Err()
+	disable()
+	fmt.Printf("Find with a transient error label: %s\n", strings.Join(classify(err), ", "))
+	// end classify errors
+}
+
+// start-fail-point
+// Makes the next matching command from this program fail as described by
+// data. Returns a function that turns off the fail point.
+func setFailPoint(client *mongo.Client, data bson.D) func() {
+	admin := client.Database("admin")
+	data = append(data, bson.E{"appName", appName})
+
+	cmd := bson.D{
+		{"configureFailPoint", "failCommand"},
+		{"mode", bson.D{{"times", 1}}},
+		{"data", data},
+	}
+	if err := admin.RunCommand(context.TODO(), cmd).Err(); err != nil {
+		log.Fatalf("failed to set the fail point, is enableTestCommands set? %v", err)
+	}
+
+	return func() {
+		cmd := bson.D{
+			{"configureFailPoint", "failCommand"},
+			{"mode", "off"},
+		}
+		if err := admin.RunCommand(context.TODO(), cmd).Err(); err != nil {
+			log.Fatalf("failed to turn off the fail point: %v", err)
+		}
+	}
+}
+
+// end-fail-point
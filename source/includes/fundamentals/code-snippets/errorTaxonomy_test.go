@@ -0,0 +1,189 @@
+// Tests that each error category applies to exactly the errors it should.
+// Run against a local replica set member started with "--setParameter
+// enableTestCommands=1":
+//
+//	MONGODB_URI=<replica set member> go test errorTaxonomy.go errorTaxonomy_test.go
+package main
+
+import (
+	"context"
+	"os"
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+)
+
+// Triggers each error and checks that classify returns exactly the expected
+// set of categories
+func TestClassify(t *testing.T) {
+	uri := os.Getenv("MONGODB_URI")
+	if uri == "" {
+		t.Skip("set MONGODB_URI to a replica set member started with enableTestCommands=1")
+	}
+
+	client, err := mongo.Connect(clientOptions(uri))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.Disconnect(context.Background())
+
+	db := client.Database("db")
+	coll := db.Collection("errors")
+	_ = coll.Drop(context.Background())
+	_ = db.Collection("validated").Drop(context.Background())
+
+	if _, err := coll.InsertOne(context.Background(), bson.D{{"_id", 1}}); err != nil {
+		t.Fatal(err)
+	}
+	validator := bson.D{{"$jsonSchema", bson.D{{"required", bson.A{"name"}}}}}
+	err = db.CreateCollection(context.Background(), "validated", options.CreateCollection().SetValidator(validator))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	scenarios := []struct {
+		name    string
+		trigger func(ctx context.Context) error
+		want    []string
+	}{
+		{
+			name: "find a missing document",
+			trigger: func(ctx context.Context) error {
+				return coll.FindOne(ctx, bson.D{{"_id", 404}}).Err()
+			},
+			want: []string{"no documents"},
+		},
+		{
+			name: "insert a duplicate _id",
+			trigger: func(ctx context.Context) error {
+				_, err := coll.InsertOne(ctx, bson.D{{"_id", 1}})
+				return err
+			},
+			want: []string{"duplicate key", "write exception"},
+		},
+		{
+			name: "insert many with a duplicate _id",
+			trigger: func(ctx context.Context) error {
+				docs := []interface{}{bson.D{{"_id", 2}}, bson.D{{"_id", 1}}}
+				_, err := coll.InsertMany(ctx, docs, options.InsertMany().SetOrdered(false))
+				return err
+			},
+			want: []string{"duplicate key", "bulk write exception"},
+		},
+		{
+			name: "insert a document that fails validation",
+			trigger: func(ctx context.Context) error {
+				_, err := db.Collection("validated").InsertOne(ctx, bson.D{{"age", 30}})
+				return err
+			},
+			want: []string{"write exception", "validation"},
+		},
+		{
+			name: "insert with a write concern error",
+			trigger: func(ctx context.Context) error {
+				// The write succeeds, but the server reports that it could
+				// not satisfy the write concern
+				disable := setFailPoint(client, bson.D{
+					{"failCommands", bson.A{"insert"}},
+					{"writeConcernError", bson.D{
+						{"code", 64},
+						{"codeName", "WriteConcernFailed"},
+						{"errmsg", "waiting for replication timed out"},
+						{"errInfo", bson.D{{"wtimeout", true}}},
+					}},
+				})
+				defer disable()
+
+				_, err := coll.InsertOne(ctx, bson.D{{"_id", 3}})
+				return err
+			},
+			want: []string{"write exception", "write concern"},
+		},
+		{
+			name: "find that exceeds its deadline",
+			trigger: func(ctx context.Context) error {
+				disable := setFailPoint(client, bson.D{
+					{"failCommands", bson.A{"find"}},
+					{"blockConnection", true},
+					{"blockTimeMS", 1000},
+				})
+				defer disable()
+
+				ctx, cancel := context.WithTimeout(ctx, 100*time.Millisecond)
+				defer cancel()
+				return coll.FindOne(ctx, bson.D{{"_id", 1}}).Err()
+			},
+			// The deadline interrupts the read of the reply, so the driver
+			// also reports a network error, which it returns as a
+			// CommandError with the "NetworkError" label
+			want: []string{"timeout", "network", "command error"},
+		},
+		{
+			name: "find on a closed connection",
+			trigger: func(ctx context.Context) error {
+				disable := setFailPoint(client, bson.D{
+					{"failCommands", bson.A{"find"}},
+					{"closeConnection", true},
+				})
+				defer disable()
+
+				return coll.FindOne(ctx, bson.D{{"_id", 1}}).Err()
+			},
+			want: []string{"network", "command error"},
+		},
+		{
+			name: "run an unknown command",
+			trigger: func(ctx context.Context) error {
+				return db.RunCommand(ctx, bson.D{{"notACommand", 1}}).Err()
+			},
+			want: []string{"command error", "command not found"},
+		},
+		{
+			name: "find that fails with a transient error label",
+			trigger: func(ctx context.Context) error {
+				disable := setFailPoint(client, bson.D{
+					{"failCommands", bson.A{"find"}},
+					{"errorCode", 112},
+					{"errorLabels", bson.A{"TransientTransactionError"}},
+				})
+				defer disable()
+
+				return coll.FindOne(ctx, bson.D{{"_id", 1}}).Err()
+			},
+			want: []string{"command error", "transient transaction"},
+		},
+	}
+
+	for _, s := range scenarios {
+		err := s.trigger(context.Background())
+		missing, unexpected := compareCategories(classify(err), s.want)
+		if len(missing) > 0 || len(unexpected) > 0 {
+			t.Errorf("%s: error %v is missing categories %v and has unexpected categories %v",
+				s.name, err, missing, unexpected)
+		}
+	}
+}
+
+// Returns the names in want that are not in got and the names in got that
+// are not in want. Both are empty when the sets are equal.
+func compareCategories(got, want []string) (missing, unexpected []string) {
+	return difference(want, got), difference(got, want)
+}
+
+// Returns the names in a that are not in b
+func difference(a, b []string) []string {
+	in := map[string]bool{}
+	for _, name := range b {
+		in[name] = true
+	}
+	var out []string
+	for _, name := range a {
+		if !in[name] {
+			out = append(out, name)
+		}
+	}
+	return out
+}
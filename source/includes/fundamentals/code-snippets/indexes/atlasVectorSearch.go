@@ -23,7 +23,7 @@ func main() {
 
 	// Connect to your Atlas cluster
 	clientOptions := options.Client().ApplyURI(uri)
-	client, err := mongo.Connect(ctx, clientOptions)
+	client, err := mongo.Connect(clientOptions)
 	if err != nil {
 		log.Fatalf("Failed to connect to the server: %v", err)
 	}
@@ -0,0 +1,307 @@
+// Creates, lists, verifies, and drops several types of indexes by using the
+// Go driver
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"time"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+)
+
+// start-user-struct
+type User struct {
+	Name       string
+	Email      string
+	Nickname   string `bson:",omitempty"`
+	City       string
+	Age        int32
+	Status     string
+	LastLogin  time.Time `bson:"last_login"`
+	CreatedAt  time.Time `bson:"created_at"`
+	Attributes bson.M
+}
+
+// end-user-struct
+
+func main() {
+	var uri string
+	if uri = os.Getenv("MONGODB_URI"); uri == "" {
+		log.Fatal("You must set your 'MONGODB_URI' environment variable. See\n\t https://www.mongodb.com/docs/drivers/go/current/usage-examples/")
+	}
+
+	client, err := mongo.Connect(options.Client().ApplyURI(uri))
+	if err != nil {
+		panic(err)
+	}
+	defer func() {
+		if err = client.Disconnect(context.TODO()); err != nil {
+			panic(err)
+		}
+	}()
+
+	// begin insert docs
+	coll := client.Database("db").Collection("users")
+	if err = coll.Drop(context.TODO()); err != nil {
+		panic(err)
+	}
+
+	now := time.Now()
+	docs := []interface{}{
+		User{Name: "Élodie", Email: "elodie@example.com", City: "Paris", Age: 34, Status: "active",
+			LastLogin: now, CreatedAt: now, Attributes: bson.M{"color": "blue", "plan": "pro"}},
+		User{Name: "Marcus", Email: "marcus@example.com", Nickname: "Ace", City: "Berlin", Age: 45, Status: "inactive",
+			LastLogin: now.Add(-72 * time.Hour), CreatedAt: now, Attributes: bson.M{"color": "green"}},
+		User{Name: "Ana", Email: "ana@example.com", City: "Paris", Age: 28, Status: "active",
+			LastLogin: now.Add(-time.Hour), CreatedAt: now, Attributes: bson.M{"plan": "free"}},
+	}
+
+	result, err := coll.InsertMany(context.TODO(), docs)
+	// end insert docs
+	if err != nil {
+		panic(err)
+	}
+	fmt.Printf("Number of documents inserted: %d\n", len(result.InsertedIDs))
+
+	fmt.Println("\nCreate Indexes:")
+	{
+		// Creates one index of each type in a single command
+		// begin create many
+		models := []mongo.IndexModel{
+			// Compound index that supports queries on "city", and on
+			// "city" and "age" together
+			{
+				Keys:    bson.D{{"city", 1}, {"age", -1}},
+				Options: options.Index().SetName("city_age"),
+			},
+			// Unique index that rejects duplicate email addresses
+			{
+				Keys:    bson.D{{"email", 1}},
+				Options: options.Index().SetName("email_unique").SetUnique(true),
+			},
+			// Partial index that includes only active users
+			{
+				Keys: bson.D{{"last_login", -1}},
+				Options: options.Index().
+					SetName("active_last_login").
+					SetPartialFilterExpression(bson.D{{"status", "active"}}),
+			},
+			// Sparse index that skips documents without a "nickname" field
+			{
+				Keys:    bson.D{{"nickname", 1}},
+				Options: options.Index().SetName("nickname_sparse").SetSparse(true),
+			},
+			// TTL index that deletes users 30 days after they are created
+			{
+				Keys: bson.D{{"created_at", 1}},
+				Options: options.Index().
+					SetName("created_at_ttl").
+					SetExpireAfterSeconds(30 * 24 * 60 * 60),
+			},
+			// Wildcard index on every field in the "attributes" document
+			{
+				Keys:    bson.D{{"attributes.$**", 1}},
+				Options: options.Index().SetName("attributes_wildcard"),
+			},
+			// Hidden index that the query planner ignores until it is
+			// unhidden
+			{
+				Keys:    bson.D{{"age", 1}},
+				Options: options.Index().SetName("age_hidden").SetHidden(true),
+			},
+			// Index that compares names case- and accent-insensitively
+			{
+				Keys: bson.D{{"name", 1}},
+				Options: options.Index().
+					SetName("name_fr").
+					SetCollation(&options.Collation{Locale: "fr", Strength: 1}),
+			},
+		}
+
+		names, err := coll.Indexes().CreateMany(context.TODO(), models)
+		if err != nil {
+			panic(err)
+		}
+		fmt.Printf("Created indexes: %v\n", names)
+		// end create many
+	}
+
+	fmt.Println("\nList Indexes:")
+	{
+		// begin list specifications
+		specs, err := coll.Indexes().ListSpecifications(context.TODO())
+		if err != nil {
+			panic(err)
+		}
+
+		for _, spec := range specs {
+			fmt.Printf("%s: keys %s", spec.Name, spec.KeysDocument)
+			if spec.Unique != nil && *spec.Unique {
+				fmt.Print(", unique")
+			}
+			if spec.Sparse != nil && *spec.Sparse {
+				fmt.Print(", sparse")
+			}
+			if spec.ExpireAfterSeconds != nil {
+				fmt.Printf(", expires after %d seconds", *spec.ExpireAfterSeconds)
+			}
+			fmt.Println()
+		}
+		// end list specifications
+
+		// Prints the options that ListSpecifications does not expose, such
+		// as partial filters, hidden flags, and collations
+		// begin list raw
+		cursor, err := coll.Indexes().List(context.TODO())
+		if err != nil {
+			panic(err)
+		}
+
+		var indexes []bson.M
+		if err = cursor.All(context.TODO(), &indexes); err != nil {
+			panic(err)
+		}
+		for _, index := range indexes {
+			for _, field := range []string{"partialFilterExpression", "hidden", "collation"} {
+				if value, ok := index[field]; ok {
+					fmt.Printf("%s: %s = %v\n", index["name"], field, value)
+				}
+			}
+		}
+		// end list raw
+	}
+
+	fmt.Println("\nConfirm Index Use:")
+	{
+		// Explains a query for each index and prints the index that the
+		// query planner chose
+		// begin explain
+		queries := []struct {
+			filter    bson.D
+			collation *options.Collation
+		}{
+			{filter: bson.D{{"city", "Paris"}, {"age", bson.D{{"$gt", 30}}}}},
+			{filter: bson.D{{"email", "ana@example.com"}}},
+			{filter: bson.D{{"status", "active"}, {"last_login", bson.D{{"$gt", now.Add(-24 * time.Hour)}}}}},
+			{filter: bson.D{{"nickname", "Ace"}}},
+			{filter: bson.D{{"created_at", bson.D{{"$lt", now.Add(time.Hour)}}}}},
+			{filter: bson.D{{"attributes.color", "blue"}}},
+			{filter: bson.D{{"age", 45}}},
+			{filter: bson.D{{"name", "elodie"}}, collation: &options.Collation{Locale: "fr", Strength: 1}},
+		}
+
+		for _, q := range queries {
+			used, err := explainIndexes(context.TODO(), coll, q.filter, q.collation)
+			if err != nil {
+				panic(err)
+			}
+			fmt.Printf("%v uses %v\n", q.filter, used)
+		}
+		// end explain
+	}
+
+	fmt.Println("\nUnhide Index:")
+	{
+		// Makes the hidden index visible to the query planner
+		// begin unhide
+		cmd := bson.D{
+			{"collMod", coll.Name()},
+			{"index", bson.D{{"name", "age_hidden"}, {"hidden", false}}},
+		}
+		if err := coll.Database().RunCommand(context.TODO(), cmd).Err(); err != nil {
+			panic(err)
+		}
+		// end unhide
+
+		filter := bson.D{{"age", 45}}
+		used, err := explainIndexes(context.TODO(), coll, filter, nil)
+		if err != nil {
+			panic(err)
+		}
+		fmt.Printf("%v uses %v\n", filter, used)
+	}
+
+	fmt.Println("\nDrop Indexes:")
+	{
+		// begin drop
+		// Drops a single index by name
+		if err := coll.Indexes().DropOne(context.TODO(), "age_hidden"); err != nil {
+			panic(err)
+		}
+
+		// Drops a single index by its key specification
+		if err := coll.Indexes().DropWithKey(context.TODO(), bson.D{{"city", 1}, {"age", -1}}); err != nil {
+			panic(err)
+		}
+
+		// Drops every remaining index except the default _id index
+		if err := coll.Indexes().DropAll(context.TODO()); err != nil {
+			panic(err)
+		}
+		// end drop
+
+		specs, err := coll.Indexes().ListSpecifications(context.TODO())
+		if err != nil {
+			panic(err)
+		}
+		fmt.Printf("Remaining indexes: %d\n", len(specs))
+	}
+}
+
+// Runs the explain command on a find operation and returns the names of the
+// indexes in the winning plan, or "COLLSCAN" if the plan scans the collection
+// begin explain func
+func explainIndexes(ctx context.Context, coll *mongo.Collection, filter bson.D, collation *options.Collation) ([]string, error) {
+	find := bson.D{{"find", coll.Name()}, {"filter", filter}}
+	if collation != nil {
+		find = append(find, bson.E{"collation", bson.D{
+			{"locale", collation.Locale},
+			{"strength", collation.Strength},
+		}})
+	}
+
+	cmd := bson.D{{"explain", find}, {"verbosity", "queryPlanner"}}
+	var result bson.Raw
+	err := coll.Database().RunCommand(ctx, cmd).Decode(&result)
+	if err != nil {
+		return nil, err
+	}
+
+	plan, err := result.LookupErr("queryPlanner", "winningPlan")
+	if err != nil {
+		return nil, err
+	}
+	return planIndexes(plan.Document()), nil
+}
+
+// Walks a query plan and collects the index names of its IXSCAN stages
+func planIndexes(plan bson.Raw) []string {
+	var names []string
+
+	elems, _ := plan.Elements()
+	for _, elem := range elems {
+		switch {
+		case elem.Key() == "indexName":
+			names = append(names, elem.Value().StringValue())
+		case elem.Key() == "stage" && elem.Value().StringValue() == "COLLSCAN":
+			names = append(names, "COLLSCAN")
+		case elem.Value().Type == bson.TypeEmbeddedDocument:
+			names = append(names, planIndexes(elem.Value().Document())...)
+		case elem.Value().Type == bson.TypeArray:
+			values, _ := elem.Value().Array().Values()
+			for _, v := range values {
+				if v.Type == bson.TypeEmbeddedDocument {
+					names = append(names, planIndexes(v.Document())...)
+				}
+			}
+		}
+	}
+	return names
+}
+
+// end explain func
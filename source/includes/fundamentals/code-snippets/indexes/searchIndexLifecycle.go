@@ -0,0 +1,106 @@
+// Creates, waits for, updates, and drops an Atlas Search index by using the
+// Go driver
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"time"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+
+	"github.com/mongodb/docs-golang/source/includes/fundamentals/code-snippets/searchindex"
+)
+
+func main() {
+	ctx := context.Background()
+
+	// Retrieves your Atlas connection string
+	uri := os.Getenv("MONGODB_ATLAS_URI")
+	if uri == "" {
+		log.Fatal("MONGODB_ATLAS_URI environment variable is not set")
+	}
+
+	// Connect to your Atlas cluster
+	client, err := mongo.Connect(options.Client().ApplyURI(uri))
+	if err != nil {
+		log.Fatalf("Failed to connect to the server: %v", err)
+	}
+	defer func() {
+		if err := client.Disconnect(ctx); err != nil {
+			log.Fatalf("Failed to disconnect: %v", err)
+		}
+	}()
+
+	// Set the namespace
+	coll := client.Database("sample_mflix").Collection("movies")
+	view := coll.SearchIndexes()
+
+	// start-create-and-wait
+	const indexName = "plot_search"
+	opts := options.SearchIndexes().SetName(indexName).SetType("search")
+
+	model := mongo.SearchIndexModel{
+		Definition: bson.D{
+			{"mappings", bson.D{
+				{"dynamic", false},
+				{"fields", bson.D{
+					{"plot", bson.D{{"type", "string"}}},
+				}},
+			}},
+		},
+		Options: opts,
+	}
+
+	if _, err = view.CreateOne(ctx, model); err != nil {
+		log.Fatalf("Failed to create the Atlas Search index: %v", err)
+	}
+
+	// Waits until the index can serve $search queries
+	status, err := searchindex.WaitForQueryable(ctx, view, indexName, 10*time.Minute)
+	if err != nil {
+		log.Fatalf("Index did not become queryable: %v", err)
+	}
+	fmt.Printf("Index %q is queryable with status %s\n", status.Name, status.Status)
+	// end-create-and-wait
+
+	// start-update-and-wait
+	// Adds the "title" field to the index definition
+	definition := bson.D{
+		{"mappings", bson.D{
+			{"dynamic", false},
+			{"fields", bson.D{
+				{"plot", bson.D{{"type", "string"}}},
+				{"title", bson.D{{"type", "string"}}},
+			}},
+		}},
+	}
+
+	if err = view.UpdateOne(ctx, indexName, definition); err != nil {
+		log.Fatalf("Failed to update the index: %v", err)
+	}
+
+	// The index stays queryable and "READY" with its old definition while
+	// it rebuilds, so this waits until it serves the new definition
+	status, err = searchindex.WaitForDefinition(ctx, view, indexName, definition, 10*time.Minute)
+	if err != nil {
+		log.Fatalf("Updated index did not become ready: %v", err)
+	}
+	fmt.Printf("Index %q is ready with the new definition\n", status.Name)
+	// end-update-and-wait
+
+	// start-drop-and-wait
+	if err = view.DropOne(ctx, indexName); err != nil {
+		log.Fatalf("Failed to drop the index: %v", err)
+	}
+
+	if err = searchindex.WaitForDrop(ctx, view, indexName, 10*time.Minute); err != nil {
+		log.Fatalf("Index was not dropped: %v", err)
+	}
+	fmt.Printf("Index %q was dropped\n", indexName)
+	// end-drop-and-wait
+}
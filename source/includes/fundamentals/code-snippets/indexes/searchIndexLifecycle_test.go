@@ -0,0 +1,24 @@
+// Tests the example against a stand-in for the Atlas Search index commands,
+// so no Atlas cluster is needed. Run with:
+//
+//	go test searchIndexLifecycle.go searchIndexLifecycle_test.go
+package main
+
+import (
+	"testing"
+
+	"github.com/mongodb/docs-golang/source/includes/fundamentals/code-snippets/searchindex/searchindextest"
+)
+
+// Runs the create, update, and drop steps of main. Each step waits for the
+// index, and main exits if a wait fails or times out.
+func TestSearchIndexLifecycle(t *testing.T) {
+	indexes, _ := searchindextest.Start(t)
+	t.Setenv("MONGODB_ATLAS_URI", indexes.URI())
+
+	main()
+
+	if v := indexes.Version("plot_search"); v != 0 {
+		t.Errorf("index remains with definition version %d after main", v)
+	}
+}
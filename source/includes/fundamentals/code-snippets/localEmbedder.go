@@ -0,0 +1,298 @@
+// Generates embeddings offline with a deterministic local embedder and runs
+// Atlas Vector Search queries by using the Go driver
+//
+// This example runs against an Atlas cluster or a local Atlas deployment,
+// such as one created with "atlas deployments setup --type local", that has
+// the sample_mflix dataset loaded.
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"log"
+	"math"
+	"os"
+	"strings"
+	"time"
+	"unicode"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+
+	"github.com/mongodb/docs-golang/source/includes/fundamentals/code-snippets/searchindex"
+)
+
+// start-embedder-interface
+// Converts text into a vector. Every vector from an Embedder has the same
+// number of dimensions.
+type Embedder interface {
+	Embed(ctx context.Context, text string) ([]float32, error)
+	Dimensions() int
+}
+
+// end-embedder-interface
+
+// start-hash-embedder
+// Embeds text without a network call by hashing its character n-grams into a
+// fixed number of buckets. The same text always produces the same vector,
+// and texts that share many n-grams produce similar vectors.
+type HashEmbedder struct {
+	dims int
+	n    int
+}
+
+// Returns a HashEmbedder that produces vectors with dims dimensions from
+// n-character n-grams
+func NewHashEmbedder(dims, n int) (HashEmbedder, error) {
+	if dims <= 0 {
+		return HashEmbedder{}, fmt.Errorf("dimensions must be positive, got %d", dims)
+	}
+	if n <= 0 {
+		return HashEmbedder{}, fmt.Errorf("n-gram length must be positive, got %d", n)
+	}
+	return HashEmbedder{dims: dims, n: n}, nil
+}
+
+func (e HashEmbedder) Dimensions() int {
+	return e.dims
+}
+
+func (e HashEmbedder) Embed(_ context.Context, text string) ([]float32, error) {
+	if e.dims <= 0 || e.n <= 0 {
+		return nil, errors.New("HashEmbedder must be created with NewHashEmbedder")
+	}
+	vector := make([]float32, e.dims)
+
+	for _, word := range strings.FieldsFunc(strings.ToLower(text), isSeparator) {
+		// Pads each word so that n-grams at its start and end differ from
+		// n-grams in its middle
+		runes := []rune(" " + word + " ")
+		for i := 0; i+e.n <= len(runes); i++ {
+			h := fnv.New32a()
+			h.Write([]byte(string(runes[i : i+e.n])))
+			sum := h.Sum32()
+
+			// Uses one bit of the hash as a sign so that unrelated n-grams
+			// that share a bucket tend to cancel out
+			bucket := sum % uint32(e.dims)
+			if sum&(1<<31) == 0 {
+				vector[bucket]++
+			} else {
+				vector[bucket]--
+			}
+		}
+	}
+
+	// Scales the vector to unit length so that the dot product of two
+	// vectors equals their cosine similarity
+	var norm float64
+	for _, v := range vector {
+		norm += float64(v) * float64(v)
+	}
+	if norm > 0 {
+		scale := float32(1 / math.Sqrt(norm))
+		for i := range vector {
+			vector[i] *= scale
+		}
+	}
+	return vector, nil
+}
+
+func isSeparator(r rune) bool {
+	return !unicode.IsLetter(r) && !unicode.IsNumber(r)
+}
+
+// end-hash-embedder
+
+const (
+	indexName     = "local_vector_index"
+	embeddingPath = "plot_embedding_local"
+)
+
+func main() {
+	ctx := context.Background()
+
+	// Retrieves your Atlas connection string
+	uri := os.Getenv("MONGODB_ATLAS_URI")
+	if uri == "" {
+		log.Fatal("MONGODB_ATLAS_URI environment variable is not set")
+	}
+
+	// Connect to your Atlas cluster
+	client, err := mongo.Connect(options.Client().ApplyURI(uri))
+	if err != nil {
+		log.Fatalf("Failed to connect to the server: %v", err)
+	}
+	defer func() { _ = client.Disconnect(ctx) }()
+
+	// Set the namespace
+	coll := client.Database("sample_mflix").Collection("embedded_movies")
+
+	embedder, err := NewHashEmbedder(256, 3)
+	if err != nil {
+		log.Fatalf("Failed to create the embedder: %v", err)
+	}
+
+	// start-embed-plots
+	// Stores an embedding of each movie plot in a new field
+	cursor, err := coll.Find(ctx,
+		bson.D{{"plot", bson.D{{"$type", "string"}}}},
+		options.Find().SetProjection(bson.D{{"plot", 1}}))
+	if err != nil {
+		log.Fatalf("Failed to retrieve movies: %v", err)
+	}
+
+	var models []mongo.WriteModel
+	for cursor.Next(ctx) {
+		var movie struct {
+			ID   bson.ObjectID `bson:"_id"`
+			Plot string        `bson:"plot"`
+		}
+		if err := cursor.Decode(&movie); err != nil {
+			log.Fatalf("Failed to decode movie: %v", err)
+		}
+
+		vector, err := embedder.Embed(ctx, movie.Plot)
+		if err != nil {
+			log.Fatalf("Failed to embed plot: %v", err)
+		}
+		models = append(models, mongo.NewUpdateOneModel().
+			SetFilter(bson.D{{"_id", movie.ID}}).
+			SetUpdate(bson.D{{"$set", bson.D{{embeddingPath, bson.NewVector(vector).Binary()}}}}))
+	}
+	if err := cursor.Err(); err != nil {
+		log.Fatalf("Failed to iterate movies: %v", err)
+	}
+
+	result, err := coll.BulkWrite(ctx, models, options.BulkWrite().SetOrdered(false))
+	if err != nil {
+		log.Fatalf("Failed to store embeddings: %v", err)
+	}
+	fmt.Printf("Stored %d embeddings\n", result.MatchedCount)
+	// end-embed-plots
+
+	// start-create-index
+	// Creates a vector search index whose dimensions match the embedder
+	type vectorDefinitionField struct {
+		Type          string `bson:"type"`
+		Path          string `bson:"path"`
+		NumDimensions int    `bson:"numDimensions"`
+		Similarity    string `bson:"similarity"`
+	}
+	type vectorDefinition struct {
+		Fields []vectorDefinitionField `bson:"fields"`
+	}
+
+	model := mongo.SearchIndexModel{
+		Definition: vectorDefinition{
+			Fields: []vectorDefinitionField{{
+				Type:          "vector",
+				Path:          embeddingPath,
+				NumDimensions: embedder.Dimensions(),
+				Similarity:    "dotProduct",
+			}},
+		},
+		Options: options.SearchIndexes().SetName(indexName).SetType("vectorSearch"),
+	}
+	if err = createOrUpdateIndex(ctx, coll.SearchIndexes(), indexName, model); err != nil {
+		log.Fatalf("Failed to create the Atlas Vector Search index: %v", err)
+	}
+
+	// Waits until the index serves queries with this definition
+	_, err = searchindex.WaitForDefinition(ctx, coll.SearchIndexes(), indexName, model.Definition, 10*time.Minute)
+	if err != nil {
+		log.Fatalf("Index did not become queryable: %v", err)
+	}
+	// end-create-index
+
+	// start-query
+	pipeline, err := vectorSearchPipeline(ctx, embedder, "a heist that goes wrong", 5)
+	if err != nil {
+		log.Fatalf("Failed to build the pipeline: %v", err)
+	}
+
+	cursor, err = coll.Aggregate(ctx, pipeline)
+	if err != nil {
+		log.Fatalf("Failed to retrieve data from the server: %v", err)
+	}
+
+	type ProjectedMovieResult struct {
+		Title string  `bson:"title"`
+		Plot  string  `bson:"plot"`
+		Score float64 `bson:"score"`
+	}
+
+	var results []ProjectedMovieResult
+	if err = cursor.All(ctx, &results); err != nil {
+		log.Fatalf("Failed to unmarshal retrieved docs to ProjectedMovieResult objects: %v", err)
+	}
+
+	for _, result := range results {
+		fmt.Printf("Title: %v \nPlot: %v \nScore: %v \n\n", result.Title, result.Plot, result.Score)
+	}
+	// end-query
+}
+
+// start-create-func
+// Creates the named search index, or updates its definition if an earlier
+// run already created it
+func createOrUpdateIndex(ctx context.Context, view mongo.SearchIndexView, name string, model mongo.SearchIndexModel) error {
+	_, err := view.CreateOne(ctx, model)
+
+	// Atlas reports an existing index name with the IndexAlreadyExists code
+	var serverErr mongo.ServerError
+	if errors.As(err, &serverErr) && serverErr.HasErrorCode(68) {
+		return view.UpdateOne(ctx, name, model.Definition)
+	}
+	return err
+}
+
+// end-create-func
+
+// start-pipeline-func
+// Builds an aggregation pipeline that embeds query with embedder and returns
+// the limit most similar movies. Returns an error if query embeds to a zero
+// vector, such as when it has no words, because a dotProduct index cannot
+// score documents against a vector without a direction.
+func vectorSearchPipeline(ctx context.Context, embedder Embedder, query string, limit int) (mongo.Pipeline, error) {
+	vector, err := embedder.Embed(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	if isZero(vector) {
+		return nil, fmt.Errorf("query %q has no words to search for", query)
+	}
+
+	vectorSearchStage := bson.D{
+		{"$vectorSearch", bson.D{
+			{"index", indexName},
+			{"path", embeddingPath},
+			{"queryVector", bson.NewVector(vector).Binary()},
+			{"numCandidates", limit * 20},
+			{"limit", limit},
+		}}}
+
+	projectStage := bson.D{
+		{"$project", bson.D{
+			{"_id", 0},
+			{"plot", 1},
+			{"title", 1},
+			{"score", bson.D{{"$meta", "vectorSearchScore"}}},
+		}}}
+
+	return mongo.Pipeline{vectorSearchStage, projectStage}, nil
+}
+
+// end-pipeline-func
+
+func isZero(vector []float32) bool {
+	for _, v := range vector {
+		if v != 0 {
+			return false
+		}
+	}
+	return true
+}
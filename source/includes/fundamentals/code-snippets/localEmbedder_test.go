@@ -0,0 +1,176 @@
+// Tests the local embedder, the pipeline, and the index creation, which uses
+// the search index stand-in in searchindex/searchindextest. Run with:
+//
+//	go test localEmbedder.go localEmbedder_test.go
+package main
+
+import (
+	"context"
+	"errors"
+	"math"
+	"reflect"
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+
+	"github.com/mongodb/docs-golang/source/includes/fundamentals/code-snippets/searchindex"
+	"github.com/mongodb/docs-golang/source/includes/fundamentals/code-snippets/searchindex/searchindextest"
+)
+
+func TestNewHashEmbedder(t *testing.T) {
+	cases := []struct {
+		dims, n int
+		fails   bool
+	}{
+		{256, 3, false},
+		{1, 1, false},
+		{0, 3, true},
+		{-8, 3, true},
+		{256, 0, true},
+	}
+	for _, c := range cases {
+		e, err := NewHashEmbedder(c.dims, c.n)
+		if c.fails != (err != nil) {
+			t.Errorf("NewHashEmbedder(%d, %d) returned error %v", c.dims, c.n, err)
+		}
+		if err == nil && e.Dimensions() != c.dims {
+			t.Errorf("NewHashEmbedder(%d, %d) has %d dimensions", c.dims, c.n, e.Dimensions())
+		}
+	}
+
+	if _, err := (HashEmbedder{}).Embed(context.Background(), "text"); err == nil {
+		t.Error("the zero HashEmbedder embedded text without an error")
+	}
+}
+
+func embed(t *testing.T, e Embedder, text string) []float32 {
+	t.Helper()
+	v, err := e.Embed(context.Background(), text)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(v) != e.Dimensions() {
+		t.Fatalf("got %d dimensions, want %d", len(v), e.Dimensions())
+	}
+	return v
+}
+
+func dot(a, b []float32) float64 {
+	var sum float64
+	for i := range a {
+		sum += float64(a[i]) * float64(b[i])
+	}
+	return sum
+}
+
+func TestEmbed(t *testing.T) {
+	e, err := NewHashEmbedder(256, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	heist := embed(t, e, "A heist that goes wrong.")
+	if again := embed(t, e, "a HEIST that goes wrong"); dot(heist, again) < 0.9999 {
+		t.Errorf("the same words embedded differently, similarity %v", dot(heist, again))
+	}
+	if n := dot(heist, heist); math.Abs(n-1) > 1e-5 {
+		t.Errorf("got squared length %v, want 1", n)
+	}
+
+	similar := embed(t, e, "the robbery and heist went wrong")
+	unrelated := embed(t, e, "penguins migrate across antarctic ice")
+	if dot(heist, similar) <= dot(heist, unrelated) {
+		t.Errorf("similar text scored %v, unrelated text scored %v", dot(heist, similar), dot(heist, unrelated))
+	}
+
+	for _, v := range embed(t, e, "  ...  ") {
+		if v != 0 {
+			t.Fatal("text with no words produced a nonzero vector")
+		}
+	}
+}
+
+type failingEmbedder struct{}
+
+func (failingEmbedder) Embed(context.Context, string) ([]float32, error) {
+	return nil, errors.New("embedding service unavailable")
+}
+
+func (failingEmbedder) Dimensions() int { return 256 }
+
+func TestVectorSearchPipeline(t *testing.T) {
+	e, err := NewHashEmbedder(256, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+
+	pipeline, err := vectorSearchPipeline(ctx, e, "a heist that goes wrong", 5)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := mongo.Pipeline{
+		{{"$vectorSearch", bson.D{
+			{"index", indexName},
+			{"path", embeddingPath},
+			{"queryVector", bson.NewVector(embed(t, e, "a heist that goes wrong")).Binary()},
+			{"numCandidates", 100},
+			{"limit", 5},
+		}}},
+		{{"$project", bson.D{
+			{"_id", 0},
+			{"plot", 1},
+			{"title", 1},
+			{"score", bson.D{{"$meta", "vectorSearchScore"}}},
+		}}},
+	}
+	if !reflect.DeepEqual(pipeline, want) {
+		t.Errorf("got pipeline\n%v\nwant\n%v", pipeline, want)
+	}
+
+	// A query without words embeds to a zero vector, which a dotProduct
+	// index cannot score against
+	for _, query := range []string{"", "  ...  "} {
+		if _, err = vectorSearchPipeline(ctx, e, query, 5); err == nil {
+			t.Errorf("built a pipeline for the query %q", query)
+		}
+	}
+	if _, err = vectorSearchPipeline(ctx, failingEmbedder{}, "heist", 5); err == nil {
+		t.Error("built a pipeline although the embedder failed")
+	}
+}
+
+// Runs the index step of main twice, as when main runs again, and checks
+// that the second run updates the existing index
+func TestCreateOrUpdateIndex(t *testing.T) {
+	indexes, client := searchindextest.Start(t)
+	view := client.Database("sample_mflix").Collection("embedded_movies").SearchIndexes()
+	ctx := context.Background()
+
+	definition := func(dims int) bson.D {
+		return bson.D{{"fields", bson.A{bson.D{
+			{"type", "vector"},
+			{"path", embeddingPath},
+			{"numDimensions", dims},
+			{"similarity", "dotProduct"},
+		}}}}
+	}
+	for run, dims := range []int{256, 512} {
+		model := mongo.SearchIndexModel{
+			Definition: definition(dims),
+			Options:    options.SearchIndexes().SetName(indexName).SetType("vectorSearch"),
+		}
+		if err := createOrUpdateIndex(ctx, view, indexName, model); err != nil {
+			t.Fatalf("run %d: %v", run+1, err)
+		}
+		if _, err := searchindex.WaitForDefinition(ctx, view, indexName, model.Definition, time.Minute); err != nil {
+			t.Fatalf("run %d: %v", run+1, err)
+		}
+		if v := indexes.Version(indexName); v != int64(run+1) {
+			t.Errorf("run %d: got definition version %d, want %d", run+1, v, run+1)
+		}
+	}
+}
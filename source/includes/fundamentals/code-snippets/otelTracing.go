@@ -0,0 +1,147 @@
+// Creates an OpenTelemetry span for each command that the driver sends by
+// using the Go driver
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"sync"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/event"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// start-command-tracer
+// Starts a span when the driver sends a command and ends it when the command
+// succeeds or fails. Each span is a child of the span in the context passed
+// to the operation.
+type CommandTracer struct {
+	tracer trace.Tracer
+
+	mu sync.Mutex
+	// Holds the spans of commands in progress by request ID
+	spans map[int64]trace.Span
+}
+
+func NewCommandTracer(tp trace.TracerProvider) *CommandTracer {
+	return &CommandTracer{
+		tracer: tp.Tracer("go.mongodb.org/mongo-driver/v2"),
+		spans:  map[int64]trace.Span{},
+	}
+}
+
+func (t *CommandTracer) Monitor() *event.CommandMonitor {
+	return &event.CommandMonitor{
+		Started:   t.started,
+		Succeeded: t.succeeded,
+		Failed:    t.failed,
+	}
+}
+
+func (t *CommandTracer) started(ctx context.Context, e *event.CommandStartedEvent) {
+	attrs := []attribute.KeyValue{
+		attribute.String("db.system", "mongodb"),
+		attribute.String("db.operation", e.CommandName),
+		attribute.String("db.name", e.DatabaseName),
+		attribute.String("db.mongodb.connection_id", e.ConnectionID),
+	}
+
+	// For commands such as insert and find, the value of the first field
+	// is the collection name
+	name := e.CommandName + " " + e.DatabaseName
+	if coll, ok := e.Command.Lookup(e.CommandName).StringValueOK(); ok {
+		attrs = append(attrs, attribute.String("db.collection", coll))
+		name += "." + coll
+	}
+
+	_, span := t.tracer.Start(ctx, name,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(attrs...))
+
+	t.mu.Lock()
+	t.spans[e.RequestID] = span
+	t.mu.Unlock()
+}
+
+func (t *CommandTracer) succeeded(_ context.Context, e *event.CommandSucceededEvent) {
+	if span, ok := t.finish(e.RequestID); ok {
+		span.End()
+	}
+}
+
+func (t *CommandTracer) failed(_ context.Context, e *event.CommandFailedEvent) {
+	if span, ok := t.finish(e.RequestID); ok {
+		span.RecordError(e.Failure)
+		span.SetStatus(codes.Error, e.Failure.Error())
+		span.End()
+	}
+}
+
+// Removes and returns the span for requestID
+func (t *CommandTracer) finish(requestID int64) (trace.Span, bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	span, ok := t.spans[requestID]
+	delete(t.spans, requestID)
+	return span, ok
+}
+
+// end-command-tracer
+
+func main() {
+	var uri string
+	if uri = os.Getenv("MONGODB_URI"); uri == "" {
+		log.Fatal("You must set your 'MONGODB_URI' environment variable. See\n\t https://www.mongodb.com/docs/drivers/go/current/usage-examples/")
+	}
+
+	fmt.Println("Driver Commands:")
+	{
+		// Records spans in memory. A service would use an exporter that
+		// sends spans to a collector instead.
+		// begin set tracer
+		exporter := tracetest.NewInMemoryExporter()
+		tp := sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter))
+		defer tp.Shutdown(context.TODO())
+
+		tracer := NewCommandTracer(tp)
+		clientOptions := options.Client().ApplyURI(uri).SetMonitor(tracer.Monitor())
+		// end set tracer
+
+		client, err := mongo.Connect(clientOptions)
+		if err != nil {
+			panic(err)
+		}
+		defer func() {
+			if err = client.Disconnect(context.TODO()); err != nil {
+				panic(err)
+			}
+		}()
+
+		// Runs operations inside a parent span so that the command spans
+		// appear as its children
+		// begin traced operations
+		ctx, parent := tp.Tracer("example").Start(context.TODO(), "handle request")
+		coll := client.Database("db").Collection("people")
+		if _, err = coll.InsertOne(ctx, bson.D{{"name", "Sam Lee"}}); err != nil {
+			panic(err)
+		}
+		if err = coll.FindOne(ctx, bson.D{{"name", "Sam Lee"}}).Err(); err != nil {
+			panic(err)
+		}
+		parent.End()
+		// end traced operations
+
+		for _, span := range exporter.GetSpans() {
+			fmt.Printf("%-25s parent %s  %v\n", span.Name, span.Parent.SpanID(), span.Attributes)
+		}
+	}
+}
@@ -0,0 +1,149 @@
+// Tests the command tracer with synthetic command events and with the
+// commands that the driver sends to the fake server. Run with:
+//
+//	go test otelTracing.go otelTracing_test.go
+package main
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/event"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/mongodb/docs-golang/source/includes/fundamentals/code-snippets/tools/fakeserver"
+)
+
+type wantSpan struct {
+	name   string
+	attrs  map[string]string
+	status codes.Code
+}
+
+// Compares the spans other than parent with want and checks that each is a
+// client span whose parent is parent
+func checkSpans(t *testing.T, exported tracetest.SpanStubs, parent trace.Span, want []wantSpan) {
+	t.Helper()
+	var spans tracetest.SpanStubs
+	for _, span := range exported {
+		if span.SpanContext.SpanID() != parent.SpanContext().SpanID() {
+			spans = append(spans, span)
+		}
+	}
+	if len(spans) != len(want) {
+		t.Fatalf("got %d spans, want %d", len(spans), len(want))
+	}
+	for i, w := range want {
+		span := spans[i]
+		if span.Name != w.name {
+			t.Errorf("span %d: got name %q, want %q", i, span.Name, w.name)
+		}
+		if span.Parent.SpanID() != parent.SpanContext().SpanID() {
+			t.Errorf("%s: parent is %s, want %s", span.Name, span.Parent.SpanID(), parent.SpanContext().SpanID())
+		}
+		if span.SpanKind != trace.SpanKindClient {
+			t.Errorf("%s: got kind %v, want client", span.Name, span.SpanKind)
+		}
+		if span.Status.Code != w.status {
+			t.Errorf("%s: got status %v, want %v", span.Name, span.Status.Code, w.status)
+		}
+
+		got := map[string]string{}
+		for _, kv := range span.Attributes {
+			got[string(kv.Key)] = kv.Value.Emit()
+		}
+		for k, v := range w.attrs {
+			if got[k] != v {
+				t.Errorf("%s: got %s=%q, want %q", span.Name, k, got[k], v)
+			}
+		}
+	}
+}
+
+// Sends synthetic command events to a CommandTracer and checks the spans in
+// an in-memory exporter
+func TestSyntheticEvents(t *testing.T) {
+	exporter := tracetest.NewInMemoryExporter()
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter))
+	monitor := NewCommandTracer(tp).Monitor()
+
+	ctx, parent := tp.Tracer("example").Start(context.Background(), "handle request")
+
+	// Starts two commands before finishing either, so that the tracer must
+	// match each finished event to its span by request ID
+	insert, _ := bson.Marshal(bson.D{{"insert", "people"}, {"documents", bson.A{bson.D{{"name", "Sam"}}}}})
+	find, _ := bson.Marshal(bson.D{{"find", "people"}, {"filter", bson.D{}}})
+	monitor.Started(ctx, &event.CommandStartedEvent{
+		Command: insert, DatabaseName: "db", CommandName: "insert", RequestID: 1, ConnectionID: "localhost:27017[-1]",
+	})
+	monitor.Started(ctx, &event.CommandStartedEvent{
+		Command: find, DatabaseName: "db", CommandName: "find", RequestID: 2, ConnectionID: "localhost:27017[-2]",
+	})
+	monitor.Failed(ctx, &event.CommandFailedEvent{
+		CommandFinishedEvent: event.CommandFinishedEvent{
+			CommandName: "find", DatabaseName: "db", RequestID: 2, Duration: time.Millisecond,
+		},
+		Failure: errors.New("connection closed"),
+	})
+	monitor.Succeeded(ctx, &event.CommandSucceededEvent{
+		CommandFinishedEvent: event.CommandFinishedEvent{
+			CommandName: "insert", DatabaseName: "db", RequestID: 1, Duration: time.Millisecond,
+		},
+	})
+	parent.End()
+
+	checkSpans(t, exporter.GetSpans(), parent, []wantSpan{
+		{"find db.people", map[string]string{"db.system": "mongodb", "db.operation": "find", "db.collection": "people"}, codes.Error},
+		{"insert db.people", map[string]string{"db.system": "mongodb", "db.operation": "insert", "db.collection": "people"}, codes.Unset},
+	})
+}
+
+// Runs operations against the fake server inside a parent span and checks
+// the span of each command, including one that fails
+func TestDriverCommands(t *testing.T) {
+	srv, err := fakeserver.Start()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer srv.Close()
+
+	exporter := tracetest.NewInMemoryExporter()
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter))
+	tracer := NewCommandTracer(tp)
+
+	client, err := mongo.Connect(options.Client().ApplyURI(srv.URI()).SetMonitor(tracer.Monitor()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.Disconnect(context.Background())
+
+	ctx, parent := tp.Tracer("example").Start(context.Background(), "handle request")
+	db := client.Database("db")
+	if _, err = db.Collection("people").InsertOne(ctx, bson.D{{"name", "Sam Lee"}}); err != nil {
+		t.Fatal(err)
+	}
+	if err = db.Collection("people").FindOne(ctx, bson.D{{"name", "Sam Lee"}}).Err(); err != nil {
+		t.Fatal(err)
+	}
+	if err = db.RunCommand(ctx, bson.D{{"notACommand", 1}}).Err(); err == nil {
+		t.Fatal("unknown command succeeded")
+	}
+	parent.End()
+
+	checkSpans(t, exporter.GetSpans(), parent, []wantSpan{
+		{"insert db.people", map[string]string{"db.operation": "insert", "db.name": "db", "db.collection": "people"}, codes.Unset},
+		{"find db.people", map[string]string{"db.operation": "find", "db.name": "db", "db.collection": "people"}, codes.Unset},
+		{"notACommand db", map[string]string{"db.operation": "notACommand", "db.name": "db"}, codes.Error},
+	})
+	if n := len(tracer.spans); n != 0 {
+		t.Errorf("%d spans were never finished", n)
+	}
+}
@@ -0,0 +1,62 @@
+// Exports command and connection pool metrics in the Prometheus text format
+// by using the Go driver
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+
+	"github.com/mongodb/docs-golang/source/includes/fundamentals/code-snippets/poolmetrics"
+)
+
+func main() {
+	var uri string
+	if uri = os.Getenv("MONGODB_URI"); uri == "" {
+		log.Fatal("You must set your 'MONGODB_URI' environment variable. See\n\t https://www.mongodb.com/docs/drivers/go/current/usage-examples/")
+	}
+
+	// begin set monitors
+	metrics := poolmetrics.NewMetrics()
+	clientOptions := options.Client().
+		ApplyURI(uri).
+		SetMonitor(metrics.CommandMonitor()).
+		SetPoolMonitor(metrics.PoolMonitor())
+	// end set monitors
+
+	client, err := mongo.Connect(clientOptions)
+	if err != nil {
+		panic(err)
+	}
+	defer func() {
+		if err = client.Disconnect(context.TODO()); err != nil {
+			panic(err)
+		}
+	}()
+
+	// Runs operations that produce latency, error, and pool metrics
+	coll := client.Database("db").Collection("metrics")
+	_ = coll.Drop(context.TODO())
+	for i := 0; i < 20; i++ {
+		if _, err = coll.InsertOne(context.TODO(), bson.D{{"_id", i % 10}}); err != nil && !mongo.IsDuplicateKeyError(err) {
+			panic(err)
+		}
+		if err = coll.FindOne(context.TODO(), bson.D{{"_id", i}}).Err(); err != nil && err != mongo.ErrNoDocuments {
+			panic(err)
+		}
+	}
+
+	// begin serve metrics
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", metrics)
+
+	fmt.Println("Serving metrics at http://localhost:2112/metrics")
+	log.Fatal(http.ListenAndServe("localhost:2112", mux))
+	// end serve metrics
+}
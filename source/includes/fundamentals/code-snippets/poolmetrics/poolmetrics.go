@@ -0,0 +1,261 @@
+// Package poolmetrics collects command and connection pool metrics from the
+// Go driver's monitors and serves them in the Prometheus text exposition
+// format.
+//
+// Example:
+//
+//	metrics := poolmetrics.NewMetrics()
+//	opts := options.Client().
+//		ApplyURI(uri).
+//		SetMonitor(metrics.CommandMonitor()).
+//		SetPoolMonitor(metrics.PoolMonitor())
+//	http.Handle("/metrics", metrics)
+package poolmetrics
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/event"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+)
+
+// Upper bounds, in seconds, of the histogram buckets
+var latencyBuckets = []float64{0.0005, 0.001, 0.005, 0.01, 0.05, 0.1, 0.5, 1}
+
+type histogram struct {
+	counts []uint64 // counts[i] holds observations <= latencyBuckets[i]
+	count  uint64
+	sum    float64
+}
+
+func (h *histogram) observe(d time.Duration) {
+	if h.counts == nil {
+		h.counts = make([]uint64, len(latencyBuckets))
+	}
+	s := d.Seconds()
+	for i, bound := range latencyBuckets {
+		if s <= bound {
+			h.counts[i]++
+		}
+	}
+	h.count++
+	h.sum += s
+}
+
+// Collects metrics from command and pool events and serves them in the
+// Prometheus text exposition format
+type Metrics struct {
+	mu sync.Mutex
+
+	commandDuration  map[string]*histogram // by command name
+	commandErrors    map[[2]string]uint64  // by command name and error code
+	checkoutWait     map[string]*histogram // by server address
+	checkoutFailures map[[2]string]uint64  // by server address and reason
+	inUse            map[string]int64      // by server address
+}
+
+// Returns a Metrics with no observations
+func NewMetrics() *Metrics {
+	return &Metrics{
+		commandDuration:  map[string]*histogram{},
+		commandErrors:    map[[2]string]uint64{},
+		checkoutWait:     map[string]*histogram{},
+		checkoutFailures: map[[2]string]uint64{},
+		inUse:            map[string]int64{},
+	}
+}
+
+// Returns a monitor that records the duration and errors of each command
+func (m *Metrics) CommandMonitor() *event.CommandMonitor {
+	return &event.CommandMonitor{
+		Succeeded: func(_ context.Context, e *event.CommandSucceededEvent) {
+			m.mu.Lock()
+			defer m.mu.Unlock()
+			m.observeCommand(e.CommandName, e.Duration)
+
+			for _, code := range replyErrorCodes(e.Reply) {
+				m.commandErrors[[2]string{e.CommandName, code}]++
+			}
+		},
+		Failed: func(_ context.Context, e *event.CommandFailedEvent) {
+			m.mu.Lock()
+			defer m.mu.Unlock()
+			m.observeCommand(e.CommandName, e.Duration)
+			m.commandErrors[[2]string{e.CommandName, errorCode(e.Failure)}]++
+		},
+	}
+}
+
+func (m *Metrics) observeCommand(name string, d time.Duration) {
+	h, ok := m.commandDuration[name]
+	if !ok {
+		h = &histogram{}
+		m.commandDuration[name] = h
+	}
+	h.observe(d)
+}
+
+// Returns each distinct error code in a successful reply. Write commands
+// report write errors and write concern errors in a successful reply, and a
+// command that fails for several documents with the same code counts once.
+func replyErrorCodes(reply bson.Raw) []string {
+	var codes []string
+	seen := map[string]bool{}
+	add := func(doc bson.Raw) {
+		n, _ := doc.Lookup("code").AsInt64OK()
+		code := strconv.FormatInt(n, 10)
+		if !seen[code] {
+			seen[code] = true
+			codes = append(codes, code)
+		}
+	}
+
+	if writeErrors, ok := reply.Lookup("writeErrors").ArrayOK(); ok {
+		values, _ := writeErrors.Values()
+		for _, v := range values {
+			if doc, ok := v.DocumentOK(); ok {
+				add(doc)
+			}
+		}
+	}
+	if wce, ok := reply.Lookup("writeConcernError").DocumentOK(); ok {
+		add(wce)
+	}
+	return codes
+}
+
+// Returns the server error code of err, "network" for network errors, or
+// "client" otherwise. Command failed events hold the driver's internal
+// error, which does not implement mongo.ServerError, so their failures count
+// as "network" or "client".
+func errorCode(err error) string {
+	var se mongo.ServerError
+	if errors.As(err, &se) {
+		if codes := se.ErrorCodes(); len(codes) > 0 && codes[0] != 0 {
+			return strconv.Itoa(codes[0])
+		}
+	}
+	if mongo.IsNetworkError(err) {
+		return "network"
+	}
+	return "client"
+}
+
+// Returns a monitor that records checkout waits, checkout failures, and
+// connections in use for each server
+func (m *Metrics) PoolMonitor() *event.PoolMonitor {
+	return &event.PoolMonitor{
+		Event: func(e *event.PoolEvent) {
+			m.mu.Lock()
+			defer m.mu.Unlock()
+
+			switch e.Type {
+			case event.ConnectionCheckedOut:
+				// Duration holds the time spent waiting for the connection
+				h, ok := m.checkoutWait[e.Address]
+				if !ok {
+					h = &histogram{}
+					m.checkoutWait[e.Address] = h
+				}
+				h.observe(e.Duration)
+				m.inUse[e.Address]++
+			case event.ConnectionCheckedIn:
+				m.inUse[e.Address]--
+			case event.ConnectionCheckOutFailed:
+				m.checkoutFailures[[2]string{e.Address, e.Reason}]++
+			}
+		},
+	}
+}
+
+// Writes every metric in the Prometheus text exposition format
+func (m *Metrics) ServeHTTP(w http.ResponseWriter, _ *http.Request) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+
+	writeHistograms(w, "mongodb_command_duration_seconds", "Duration of commands sent to MongoDB.",
+		"command", m.commandDuration)
+
+	writeHeader(w, "mongodb_command_errors_total", "Commands that returned an error, counted once for each distinct error code.", "counter")
+	for _, k := range sortedPairs(m.commandErrors) {
+		fmt.Fprintf(w, "mongodb_command_errors_total{command=%s,code=%s} %d\n",
+			quote(k[0]), quote(k[1]), m.commandErrors[k])
+	}
+
+	writeHistograms(w, "mongodb_pool_checkout_wait_seconds", "Time spent waiting to check out a connection.",
+		"address", m.checkoutWait)
+
+	writeHeader(w, "mongodb_pool_checkout_failures_total", "Connection checkouts that failed, by reason.", "counter")
+	for _, k := range sortedPairs(m.checkoutFailures) {
+		fmt.Fprintf(w, "mongodb_pool_checkout_failures_total{address=%s,reason=%s} %d\n",
+			quote(k[0]), quote(k[1]), m.checkoutFailures[k])
+	}
+
+	writeHeader(w, "mongodb_pool_connections_in_use", "Connections checked out of the pool.", "gauge")
+	for _, addr := range sortedKeys(m.inUse) {
+		fmt.Fprintf(w, "mongodb_pool_connections_in_use{address=%s} %d\n", quote(addr), m.inUse[addr])
+	}
+}
+
+func writeHeader(w io.Writer, name, help, typ string) {
+	fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s %s\n", name, help, name, typ)
+}
+
+func writeHistograms(w io.Writer, name, help, label string, hs map[string]*histogram) {
+	writeHeader(w, name, help, "histogram")
+	for _, key := range sortedKeys(hs) {
+		h := hs[key]
+		l := label + "=" + quote(key)
+		for i, bound := range latencyBuckets {
+			fmt.Fprintf(w, "%s_bucket{%s,le=%q} %d\n", name, l, formatFloat(bound), h.counts[i])
+		}
+		fmt.Fprintf(w, "%s_bucket{%s,le=\"+Inf\"} %d\n", name, l, h.count)
+		fmt.Fprintf(w, "%s_sum{%s} %s\n", name, l, formatFloat(h.sum))
+		fmt.Fprintf(w, "%s_count{%s} %d\n", name, l, h.count)
+	}
+}
+
+// Quotes a label value, escaping backslashes, double quotes, and newlines
+func quote(s string) string {
+	r := strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+	return `"` + r.Replace(s) + `"`
+}
+
+func formatFloat(f float64) string {
+	return strconv.FormatFloat(f, 'g', -1, 64)
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func sortedPairs(m map[[2]string]uint64) [][2]string {
+	keys := make([][2]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i][0] != keys[j][0] {
+			return keys[i][0] < keys[j][0]
+		}
+		return keys[i][1] < keys[j][1]
+	})
+	return keys
+}
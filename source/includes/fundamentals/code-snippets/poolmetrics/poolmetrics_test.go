@@ -0,0 +1,192 @@
+package poolmetrics
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/event"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+
+	"github.com/mongodb/docs-golang/source/includes/fundamentals/code-snippets/tools/fakeserver"
+)
+
+// Scrapes the handler with an httptest server and returns the response body
+func scrape(t *testing.T, m *Metrics) string {
+	t.Helper()
+	server := httptest.NewServer(m)
+	defer server.Close()
+
+	resp, err := http.Get(server.URL + "/metrics")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if ct := resp.Header.Get("Content-Type"); !strings.HasPrefix(ct, "text/plain; version=0.0.4") {
+		t.Errorf("got content type %q, want the Prometheus text format", ct)
+	}
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(body)
+}
+
+// Sends synthetic events to the monitors and compares the scraped metrics
+// with golden output
+func TestGolden(t *testing.T) {
+	metrics := NewMetrics()
+	cmd := metrics.CommandMonitor()
+	pool := metrics.PoolMonitor()
+	ctx := context.Background()
+
+	const addr = "localhost:27017"
+	pool.Event(&event.PoolEvent{Type: event.ConnectionCheckedOut, Address: addr, Duration: 200 * time.Microsecond})
+	pool.Event(&event.PoolEvent{Type: event.ConnectionCheckedOut, Address: addr, Duration: 3 * time.Millisecond})
+	pool.Event(&event.PoolEvent{Type: event.ConnectionCheckedIn, Address: addr})
+	pool.Event(&event.PoolEvent{Type: event.ConnectionCheckOutFailed, Address: addr, Reason: "timeout"})
+
+	finished := func(name string, d time.Duration) event.CommandFinishedEvent {
+		return event.CommandFinishedEvent{CommandName: name, DatabaseName: "db", Duration: d}
+	}
+	// Two write errors with the same code count as one error
+	reply, _ := bson.Marshal(bson.D{
+		{"ok", 1},
+		{"n", 0},
+		{"writeErrors", bson.A{
+			bson.D{{"index", 0}, {"code", 11000}, {"errmsg", "duplicate key"}},
+			bson.D{{"index", 1}, {"code", 11000}, {"errmsg", "duplicate key"}},
+		}},
+		{"writeConcernError", bson.D{{"code", 64}, {"errmsg", "waiting for replication timed out"}}},
+	})
+	cmd.Succeeded(ctx, &event.CommandSucceededEvent{CommandFinishedEvent: finished("insert", 2*time.Millisecond), Reply: reply})
+	cmd.Succeeded(ctx, &event.CommandSucceededEvent{CommandFinishedEvent: finished("find", 400*time.Microsecond), Reply: bson.Raw{}})
+	cmd.Failed(ctx, &event.CommandFailedEvent{
+		CommandFinishedEvent: finished("find", 20*time.Millisecond),
+		Failure:              mongo.CommandError{Code: 50, Message: "operation exceeded time limit"},
+	})
+
+	golden := `# HELP mongodb_command_duration_seconds Duration of commands sent to MongoDB.
+# TYPE mongodb_command_duration_seconds histogram
+mongodb_command_duration_seconds_bucket{command="find",le="0.0005"} 1
+mongodb_command_duration_seconds_bucket{command="find",le="0.001"} 1
+mongodb_command_duration_seconds_bucket{command="find",le="0.005"} 1
+mongodb_command_duration_seconds_bucket{command="find",le="0.01"} 1
+mongodb_command_duration_seconds_bucket{command="find",le="0.05"} 2
+mongodb_command_duration_seconds_bucket{command="find",le="0.1"} 2
+mongodb_command_duration_seconds_bucket{command="find",le="0.5"} 2
+mongodb_command_duration_seconds_bucket{command="find",le="1"} 2
+mongodb_command_duration_seconds_bucket{command="find",le="+Inf"} 2
+mongodb_command_duration_seconds_sum{command="find"} 0.0204
+mongodb_command_duration_seconds_count{command="find"} 2
+mongodb_command_duration_seconds_bucket{command="insert",le="0.0005"} 0
+mongodb_command_duration_seconds_bucket{command="insert",le="0.001"} 0
+mongodb_command_duration_seconds_bucket{command="insert",le="0.005"} 1
+mongodb_command_duration_seconds_bucket{command="insert",le="0.01"} 1
+mongodb_command_duration_seconds_bucket{command="insert",le="0.05"} 1
+mongodb_command_duration_seconds_bucket{command="insert",le="0.1"} 1
+mongodb_command_duration_seconds_bucket{command="insert",le="0.5"} 1
+mongodb_command_duration_seconds_bucket{command="insert",le="1"} 1
+mongodb_command_duration_seconds_bucket{command="insert",le="+Inf"} 1
+mongodb_command_duration_seconds_sum{command="insert"} 0.002
+mongodb_command_duration_seconds_count{command="insert"} 1
+# HELP mongodb_command_errors_total Commands that returned an error, counted once for each distinct error code.
+# TYPE mongodb_command_errors_total counter
+mongodb_command_errors_total{command="find",code="50"} 1
+mongodb_command_errors_total{command="insert",code="11000"} 1
+mongodb_command_errors_total{command="insert",code="64"} 1
+# HELP mongodb_pool_checkout_wait_seconds Time spent waiting to check out a connection.
+# TYPE mongodb_pool_checkout_wait_seconds histogram
+mongodb_pool_checkout_wait_seconds_bucket{address="localhost:27017",le="0.0005"} 1
+mongodb_pool_checkout_wait_seconds_bucket{address="localhost:27017",le="0.001"} 1
+mongodb_pool_checkout_wait_seconds_bucket{address="localhost:27017",le="0.005"} 2
+mongodb_pool_checkout_wait_seconds_bucket{address="localhost:27017",le="0.01"} 2
+mongodb_pool_checkout_wait_seconds_bucket{address="localhost:27017",le="0.05"} 2
+mongodb_pool_checkout_wait_seconds_bucket{address="localhost:27017",le="0.1"} 2
+mongodb_pool_checkout_wait_seconds_bucket{address="localhost:27017",le="0.5"} 2
+mongodb_pool_checkout_wait_seconds_bucket{address="localhost:27017",le="1"} 2
+mongodb_pool_checkout_wait_seconds_bucket{address="localhost:27017",le="+Inf"} 2
+mongodb_pool_checkout_wait_seconds_sum{address="localhost:27017"} 0.0032
+mongodb_pool_checkout_wait_seconds_count{address="localhost:27017"} 2
+# HELP mongodb_pool_checkout_failures_total Connection checkouts that failed, by reason.
+# TYPE mongodb_pool_checkout_failures_total counter
+mongodb_pool_checkout_failures_total{address="localhost:27017",reason="timeout"} 1
+# HELP mongodb_pool_connections_in_use Connections checked out of the pool.
+# TYPE mongodb_pool_connections_in_use gauge
+mongodb_pool_connections_in_use{address="localhost:27017"} 1
+`
+	if got := scrape(t, metrics); got != golden {
+		t.Errorf("scraped metrics differ from golden output\ngot:\n%s\nwant:\n%s", got, golden)
+	}
+}
+
+func TestErrorCode(t *testing.T) {
+	cases := []struct {
+		name string
+		err  error
+		want string
+	}{
+		{"command error", mongo.CommandError{Code: 59}, "59"},
+		{"wrapped command error", errors.Join(errors.New("context"), mongo.CommandError{Code: 11600}), "11600"},
+		{"network error", mongo.CommandError{Labels: []string{"NetworkError"}}, "network"},
+		{"client error", errors.New("cannot marshal"), "client"},
+	}
+	for _, c := range cases {
+		if got := errorCode(c.err); got != c.want {
+			t.Errorf("%s: got %q, want %q", c.name, got, c.want)
+		}
+	}
+}
+
+// Runs commands against the fake server and checks the metrics that the
+// driver's events produce
+func TestDriverEvents(t *testing.T) {
+	srv, err := fakeserver.Start()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer srv.Close()
+
+	metrics := NewMetrics()
+	client, err := mongo.Connect(options.Client().
+		ApplyURI(srv.URI()).
+		SetMonitor(metrics.CommandMonitor()).
+		SetPoolMonitor(metrics.PoolMonitor()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.Disconnect(context.Background())
+
+	ctx := context.Background()
+	db := client.Database("db")
+
+	// One insert command that fails for three documents with the same code
+	docs := []interface{}{bson.D{{"_id", 1}}, bson.D{{"_id", 1}}, bson.D{{"_id", 1}}, bson.D{{"_id", 1}}}
+	if _, err = db.Collection("metrics").InsertMany(ctx, docs, options.InsertMany().SetOrdered(false)); !mongo.IsDuplicateKeyError(err) {
+		t.Fatalf("got error %v, want a duplicate key error", err)
+	}
+	if err = db.RunCommand(ctx, bson.D{{"notACommand", 1}}).Err(); err == nil {
+		t.Fatal("unknown command succeeded")
+	}
+
+	body := scrape(t, metrics)
+	for _, want := range []string{
+		`mongodb_command_errors_total{command="insert",code="11000"} 1` + "\n",
+		// The failed event holds the driver's internal error, which does not
+		// expose the server's error code
+		`mongodb_command_errors_total{command="notACommand",code="client"} 1` + "\n",
+		`mongodb_command_duration_seconds_count{command="insert"} 1` + "\n",
+		`mongodb_pool_connections_in_use{address="` + srv.Addr() + `"} 0` + "\n",
+	} {
+		if !strings.Contains(body, want) {
+			t.Errorf("scraped metrics do not contain %q:\n%s", want, body)
+		}
+	}
+}
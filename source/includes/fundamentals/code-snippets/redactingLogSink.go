@@ -0,0 +1,229 @@
+// Removes sensitive values from driver log messages before another log sink
+// writes them by using the Go driver
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"regexp"
+	"strings"
+	"sync"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+)
+
+const redacted = "REDACTED"
+
+// start-redacting-sink
+// Wraps an options.LogSink and redacts the values of matching fields in
+// logged commands and replies, and the credentials in connection strings.
+//
+// Each path is a dotted list of field names that matches the end of a
+// field's path, ignoring array indexes. An asterisk matches any one field
+// name. For example, "ssn" matches an "ssn" field at any depth, and
+// "*.token" matches a "token" field inside any embedded document.
+type RedactingSink struct {
+	inner options.LogSink
+	paths [][]string
+	// Matches field names in documents that the driver truncated to the
+	// maximum document length and that are no longer valid JSON
+	fallback *regexp.Regexp
+}
+
+var uriCredentials = regexp.MustCompile(`(mongodb(?:\+srv)?://)[^@/\s"]+@`)
+
+func NewRedactingSink(inner options.LogSink, paths ...string) *RedactingSink {
+	s := &RedactingSink{inner: inner}
+
+	var names []string
+	for _, p := range paths {
+		segments := strings.Split(p, ".")
+		s.paths = append(s.paths, segments)
+		if last := segments[len(segments)-1]; last != "*" {
+			names = append(names, regexp.QuoteMeta(last))
+		}
+	}
+	if len(names) > 0 {
+		s.fallback = regexp.MustCompile(`("(?:` + strings.Join(names, "|") + `)"\s*:\s*)("(?:[^"\\]|\\.)*"?|[^,}\]]*)`)
+	}
+	return s
+}
+
+func (s *RedactingSink) Info(level int, msg string, keysAndValues ...interface{}) {
+	s.inner.Info(level, s.redactString(msg), s.redactKeysAndValues(keysAndValues)...)
+}
+
+func (s *RedactingSink) Error(err error, msg string, keysAndValues ...interface{}) {
+	if err != nil {
+		if text := s.redactString(err.Error()); text != err.Error() {
+			err = errors.New(text)
+		}
+	}
+	s.inner.Error(err, s.redactString(msg), s.redactKeysAndValues(keysAndValues)...)
+}
+
+// Returns a copy of keysAndValues with redacted values
+func (s *RedactingSink) redactKeysAndValues(keysAndValues []interface{}) []interface{} {
+	out := make([]interface{}, len(keysAndValues))
+	copy(out, keysAndValues)
+
+	for i := 1; i < len(out); i += 2 {
+		value, ok := out[i].(string)
+		if !ok {
+			continue
+		}
+		switch out[i-1] {
+		case "command", "reply":
+			out[i] = s.redactDocument(value)
+		default:
+			out[i] = s.redactString(value)
+		}
+	}
+	return out
+}
+
+// Redacts a document that the driver logged as relaxed Extended JSON
+func (s *RedactingSink) redactDocument(doc string) string {
+	var d bson.D
+	if err := bson.UnmarshalExtJSON([]byte(doc), false, &d); err != nil {
+		// Falls back to matching field names in the text
+		if s.fallback != nil {
+			doc = s.fallback.ReplaceAllString(doc, `${1}"`+redacted+`"`)
+		}
+		return s.redactString(doc)
+	}
+
+	out, err := bson.MarshalExtJSON(s.redactValue(nil, d), false, false)
+	if err != nil {
+		return redacted
+	}
+	return string(out)
+}
+
+// Returns v with the values of matching fields replaced. path holds the
+// names of the fields that contain v.
+func (s *RedactingSink) redactValue(path []string, v interface{}) interface{} {
+	switch v := v.(type) {
+	case bson.D:
+		out := make(bson.D, len(v))
+		for i, e := range v {
+			fieldPath := append(path[:len(path):len(path)], e.Key)
+			if s.matches(fieldPath) {
+				out[i] = bson.E{Key: e.Key, Value: redacted}
+			} else {
+				out[i] = bson.E{Key: e.Key, Value: s.redactValue(fieldPath, e.Value)}
+			}
+		}
+		return out
+	case bson.A:
+		out := make(bson.A, len(v))
+		for i, e := range v {
+			out[i] = s.redactValue(path, e)
+		}
+		return out
+	case string:
+		return s.redactString(v)
+	default:
+		return v
+	}
+}
+
+// Reports whether any configured path matches the end of fieldPath
+func (s *RedactingSink) matches(fieldPath []string) bool {
+	for _, p := range s.paths {
+		if len(p) > len(fieldPath) {
+			continue
+		}
+		suffix := fieldPath[len(fieldPath)-len(p):]
+		matched := true
+		for i := range p {
+			if p[i] != "*" && p[i] != suffix[i] {
+				matched = false
+				break
+			}
+		}
+		if matched {
+			return true
+		}
+	}
+	return false
+}
+
+// Replaces the user name and password in any connection string in s
+func (s *RedactingSink) redactString(str string) string {
+	return uriCredentials.ReplaceAllString(str, "${1}"+redacted+"@")
+}
+
+// end-redacting-sink
+
+// Stores the messages that reach it and optionally prints them
+type recordingSink struct {
+	mu      sync.Mutex
+	records []string
+	print   bool
+}
+
+func (r *recordingSink) Info(level int, msg string, keysAndValues ...interface{}) {
+	r.record(fmt.Sprintf("level=%d msg=%q %v", level, msg, keysAndValues))
+}
+
+func (r *recordingSink) Error(err error, msg string, keysAndValues ...interface{}) {
+	r.record(fmt.Sprintf("error=%q msg=%q %v", err, msg, keysAndValues))
+}
+
+func (r *recordingSink) record(line string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.records = append(r.records, line)
+	if r.print {
+		fmt.Println(line)
+	}
+}
+
+func main() {
+	var uri string
+	if uri = os.Getenv("MONGODB_URI"); uri == "" {
+		log.Fatal("You must set your 'MONGODB_URI' environment variable. See\n\t https://www.mongodb.com/docs/drivers/go/current/usage-examples/")
+	}
+
+	fmt.Println("Driver Messages:")
+	{
+		// begin set redacting sink
+		inner := &recordingSink{print: true}
+		sink := NewRedactingSink(inner, "ssn", "password", "*.token")
+
+		loggerOptions := options.
+			Logger().
+			SetSink(sink).
+			SetComponentLevel(options.LogComponentCommand, options.LogLevelDebug)
+
+		clientOptions := options.
+			Client().
+			ApplyURI(uri).
+			SetLoggerOptions(loggerOptions)
+		// end set redacting sink
+
+		client, err := mongo.Connect(clientOptions)
+		if err != nil {
+			panic(err)
+		}
+
+		coll := client.Database("db").Collection("people")
+		doc := bson.D{
+			{"name", "Sam Lee"},
+			{"ssn", "123-45-6789"},
+			{"session", bson.D{{"token", "s3cr3t-t0k3n"}}},
+		}
+		if _, err = coll.InsertOne(context.TODO(), doc); err != nil {
+			panic(err)
+		}
+		if err = client.Disconnect(context.TODO()); err != nil {
+			panic(err)
+		}
+	}
+}
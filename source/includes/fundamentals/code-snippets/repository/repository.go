@@ -0,0 +1,264 @@
+// Package repository reads and writes documents of one Go type in one
+// collection through a generic Repository type. A Repository decodes
+// results into the type, and can mark documents as deleted instead of
+// removing them and maintain creation and update times.
+//
+// Example:
+//
+//	type Tea struct {
+//		ID     bson.ObjectID `bson:"_id,omitempty"`
+//		Type   string        `bson:"type"`
+//		Rating int32         `bson:"rating"`
+//	}
+//
+//	teas := repository.New[Tea](coll, repository.Options{Timestamps: true})
+//	id, err := teas.Create(ctx, Tea{Type: "Masala", Rating: 10})
+//	tea, err := teas.Get(ctx, id)
+package repository
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+)
+
+// Configures optional behavior of a Repository
+type Options struct {
+	// Marks documents as deleted by setting "deleted_at" instead of
+	// removing them, and hides marked documents from Get, List, Update, and
+	// Delete
+	SoftDelete bool
+	// Sets "created_at" when a document is inserted and "updated_at" when
+	// it is inserted or changed
+	Timestamps bool
+	// Returns the current time for timestamps. Defaults to time.Now.
+	Now func() time.Time
+}
+
+// Reads and writes documents of type T in one collection
+type Repository[T any] struct {
+	coll *mongo.Collection
+	opts Options
+}
+
+// Returns a Repository for the documents in coll
+func New[T any](coll *mongo.Collection, opts Options) *Repository[T] {
+	if opts.Now == nil {
+		opts.Now = time.Now
+	}
+	return &Repository[T]{coll: coll, opts: opts}
+}
+
+// Selects the sort order and page of a List call. Page numbers start at 1,
+// and a PageSize of 0 returns every matching document.
+type ListOptions struct {
+	Sort     bson.D
+	Page     int64
+	PageSize int64
+}
+
+// Returns the document with the given _id, or mongo.ErrNoDocuments
+func (r *Repository[T]) Get(ctx context.Context, id interface{}) (T, error) {
+	var doc T
+	err := r.coll.FindOne(ctx, r.visible(bson.D{{"_id", id}})).Decode(&doc)
+	return doc, err
+}
+
+// Returns the documents that match filter
+func (r *Repository[T]) List(ctx context.Context, filter bson.D, lo ListOptions) ([]T, error) {
+	opts := options.Find()
+	if lo.Sort != nil {
+		opts.SetSort(lo.Sort)
+	}
+	if lo.PageSize > 0 {
+		page := max(lo.Page, 1)
+		opts.SetSkip((page - 1) * lo.PageSize).SetLimit(lo.PageSize)
+	}
+
+	cursor, err := r.coll.Find(ctx, r.visible(filter), opts)
+	if err != nil {
+		return nil, err
+	}
+	docs := []T{}
+	if err = cursor.All(ctx, &docs); err != nil {
+		return nil, err
+	}
+	return docs, nil
+}
+
+// Inserts doc and returns its _id
+func (r *Repository[T]) Create(ctx context.Context, doc T) (interface{}, error) {
+	d, err := toDocument(doc)
+	if err != nil {
+		return nil, err
+	}
+	if r.opts.Timestamps {
+		now := r.opts.Now()
+		d = setField(setField(d, "created_at", now), "updated_at", now)
+	}
+
+	result, err := r.coll.InsertOne(ctx, d)
+	if err != nil {
+		return nil, err
+	}
+	return result.InsertedID, nil
+}
+
+// Applies update to the document with the given _id. Returns
+// mongo.ErrNoDocuments if no visible document has that _id.
+//
+// update must be a document of update operators, not an aggregation
+// pipeline. With Timestamps set, Update adds "updated_at" to its $set
+// operator, which can hold a bson.D, a bson.M, or any value that marshals to
+// a document.
+func (r *Repository[T]) Update(ctx context.Context, id interface{}, update bson.D) error {
+	if r.opts.Timestamps {
+		var err error
+		if update, err = withSet(update, bson.E{"updated_at", r.opts.Now()}); err != nil {
+			return err
+		}
+	}
+
+	result, err := r.coll.UpdateOne(ctx, r.visible(bson.D{{"_id", id}}), update)
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return mongo.ErrNoDocuments
+	}
+	return nil
+}
+
+// Replaces the fields of the document that matches filter with the fields of
+// doc, or inserts doc if no document matches. Reports whether it inserted a
+// document. The _id field of doc is ignored.
+func (r *Repository[T]) Upsert(ctx context.Context, filter bson.D, doc T) (bool, error) {
+	d, err := toDocument(doc)
+	if err != nil {
+		return false, err
+	}
+
+	set := bson.D{}
+	for _, e := range d {
+		// Leaves "created_at" to $setOnInsert so that it keeps the time of
+		// the first insert
+		if e.Key == "_id" || r.opts.Timestamps && e.Key == "created_at" {
+			continue
+		}
+		set = append(set, e)
+	}
+	update := bson.D{{"$set", set}}
+	if r.opts.Timestamps {
+		now := r.opts.Now()
+		update = bson.D{
+			{"$set", setField(set, "updated_at", now)},
+			{"$setOnInsert", bson.D{{"created_at", now}}},
+		}
+	}
+	if r.opts.SoftDelete {
+		// Restores a soft-deleted document instead of inserting a duplicate
+		update = append(update, bson.E{"$unset", bson.D{{"deleted_at", ""}}})
+	}
+
+	result, err := r.coll.UpdateOne(ctx, filter, update, options.UpdateOne().SetUpsert(true))
+	if err != nil {
+		return false, err
+	}
+	return result.UpsertedCount == 1, nil
+}
+
+// Deletes the document with the given _id, or marks it as deleted if
+// SoftDelete is set. Returns mongo.ErrNoDocuments if no visible document has
+// that _id.
+func (r *Repository[T]) Delete(ctx context.Context, id interface{}) error {
+	filter := r.visible(bson.D{{"_id", id}})
+
+	if !r.opts.SoftDelete {
+		result, err := r.coll.DeleteOne(ctx, filter)
+		if err != nil {
+			return err
+		}
+		if result.DeletedCount == 0 {
+			return mongo.ErrNoDocuments
+		}
+		return nil
+	}
+
+	result, err := r.coll.UpdateOne(ctx, filter, bson.D{{"$set", bson.D{{"deleted_at", r.opts.Now()}}}})
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return mongo.ErrNoDocuments
+	}
+	return nil
+}
+
+// Adds a condition that excludes soft-deleted documents to filter
+func (r *Repository[T]) visible(filter bson.D) bson.D {
+	if !r.opts.SoftDelete {
+		return filter
+	}
+	return bson.D{{"$and", bson.A{filter, bson.D{{"deleted_at", bson.D{{"$exists", false}}}}}}}
+}
+
+// Adds field to the $set operator of update, creating the operator if the
+// update does not have one. A bson.M operand stays a bson.M, and any other
+// operand is converted to a bson.D. update itself is not modified.
+func withSet(update bson.D, field bson.E) (bson.D, error) {
+	out := make(bson.D, 0, len(update)+1)
+	found := false
+	for _, e := range update {
+		if e.Key == "$set" {
+			switch set := e.Value.(type) {
+			case bson.D:
+				e.Value = setField(append(bson.D{}, set...), field.Key, field.Value)
+			case bson.M:
+				m := make(bson.M, len(set)+1)
+				for k, v := range set {
+					m[k] = v
+				}
+				m[field.Key] = field.Value
+				e.Value = m
+			default:
+				d, err := toDocument(set)
+				if err != nil {
+					return nil, fmt.Errorf("cannot add %q to $set: %w", field.Key, err)
+				}
+				e.Value = setField(d, field.Key, field.Value)
+			}
+			found = true
+		}
+		out = append(out, e)
+	}
+	if !found {
+		out = append(out, bson.E{"$set", bson.D{field}})
+	}
+	return out, nil
+}
+
+// Converts v to a bson.D so that the repository can add fields to it
+func toDocument(v interface{}) (bson.D, error) {
+	data, err := bson.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	var d bson.D
+	err = bson.Unmarshal(data, &d)
+	return d, err
+}
+
+// Sets key to value in d, replacing an existing field with the same key
+func setField(d bson.D, key string, value interface{}) bson.D {
+	for i := range d {
+		if d[i].Key == key {
+			d[i].Value = value
+			return d
+		}
+	}
+	return append(d, bson.E{key, value})
+}
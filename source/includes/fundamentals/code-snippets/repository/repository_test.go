@@ -0,0 +1,263 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+
+	"github.com/mongodb/docs-golang/source/includes/fundamentals/code-snippets/tools/fakeserver"
+)
+
+type Tea struct {
+	ID        bson.ObjectID `bson:"_id,omitempty"`
+	Type      string        `bson:"type"`
+	Rating    int32         `bson:"rating"`
+	CreatedAt time.Time     `bson:"created_at,omitempty"`
+	UpdatedAt time.Time     `bson:"updated_at,omitempty"`
+}
+
+var now = time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+
+// Starts a fake server and returns a collection on it
+func startServer(t *testing.T) (*fakeserver.Server, *mongo.Collection) {
+	t.Helper()
+	srv, err := fakeserver.Start()
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { srv.Close() })
+
+	client, err := mongo.Connect(options.Client().ApplyURI(srv.URI()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { client.Disconnect(context.Background()) })
+	return srv, client.Database("db").Collection("tea")
+}
+
+func TestCreateGetList(t *testing.T) {
+	_, coll := startServer(t)
+	ctx := context.Background()
+	teas := New[Tea](coll, Options{SoftDelete: true, Timestamps: true, Now: func() time.Time { return now }})
+
+	var masalaID interface{}
+	for _, tea := range []Tea{
+		{Type: "Masala", Rating: 10},
+		{Type: "English Breakfast", Rating: 6},
+		{Type: "Oolong", Rating: 7},
+		{Type: "Assam", Rating: 5},
+		{Type: "Earl Grey", Rating: 8},
+	} {
+		id, err := teas.Create(ctx, tea)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if tea.Type == "Masala" {
+			masalaID = id
+		}
+	}
+
+	masala, err := teas.Get(ctx, masalaID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if masala.Type != "Masala" || !masala.CreatedAt.Equal(now) || !masala.UpdatedAt.Equal(now) {
+		t.Errorf("got %+v, want Masala created and updated at %v", masala, now)
+	}
+
+	page, err := teas.List(ctx, bson.D{{"rating", bson.D{{"$gt", 5}}}}, ListOptions{
+		Sort:     bson.D{{"rating", -1}},
+		Page:     2,
+		PageSize: 2,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(page) != 2 || page[0].Type != "Oolong" || page[1].Type != "English Breakfast" {
+		t.Errorf("got page %+v, want Oolong and English Breakfast", page)
+	}
+
+	// Hides a document that another program soft-deleted
+	deleted, err := coll.InsertOne(ctx, bson.D{{"type", "Matcha"}, {"rating", 9}, {"deleted_at", now}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = teas.Get(ctx, deleted.InsertedID); !errors.Is(err, mongo.ErrNoDocuments) {
+		t.Errorf("Get of a soft-deleted document returned %v, want mongo.ErrNoDocuments", err)
+	}
+	all, err := teas.List(ctx, bson.D{}, ListOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(all) != 5 {
+		t.Errorf("List returned %d documents, want the 5 that are not deleted", len(all))
+	}
+}
+
+// Returns the stored document with the given _id, including soft-deleted
+// documents
+func stored(t *testing.T, coll *mongo.Collection, id interface{}) bson.M {
+	t.Helper()
+	var doc bson.M
+	if err := coll.FindOne(context.Background(), bson.D{{"_id", id}}).Decode(&doc); err != nil {
+		t.Fatalf("find %v: %v", id, err)
+	}
+	return doc
+}
+
+// Applies each form of $set and checks that Update also sets "updated_at"
+func TestUpdate(t *testing.T) {
+	_, coll := startServer(t)
+	ctx := context.Background()
+	clock := now
+	teas := New[Tea](coll, Options{SoftDelete: true, Timestamps: true, Now: func() time.Time { return clock }})
+
+	id, err := teas.Create(ctx, Tea{Type: "Masala", Rating: 5})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cases := []struct {
+		name   string
+		update bson.D
+		want   Tea
+	}{
+		{"no $set", bson.D{{"$inc", bson.D{{"rating", int32(1)}}}}, Tea{Type: "Masala", Rating: 6}},
+		{"bson.D $set", bson.D{{"$set", bson.D{{"rating", int32(9)}}}}, Tea{Type: "Masala", Rating: 9}},
+		{"bson.M $set", bson.D{{"$set", bson.M{"rating": int32(7), "type": "Chai"}}}, Tea{Type: "Chai", Rating: 7}},
+		{"struct $set", bson.D{{"$set", struct {
+			Rating int32 `bson:"rating"`
+		}{8}}}, Tea{Type: "Chai", Rating: 8}},
+		{"$set that replaces updated_at", bson.D{{"$set", bson.D{{"updated_at", time.Time{}}}}}, Tea{Type: "Chai", Rating: 8}},
+	}
+	for i, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			clock = now.Add(time.Duration(i+1) * time.Hour)
+			if err := teas.Update(ctx, id, c.update); err != nil {
+				t.Fatal(err)
+			}
+
+			got, err := teas.Get(ctx, id)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got.Type != c.want.Type || got.Rating != c.want.Rating {
+				t.Errorf("got %s rated %d, want %s rated %d", got.Type, got.Rating, c.want.Type, c.want.Rating)
+			}
+			if !got.CreatedAt.Equal(now) || !got.UpdatedAt.Equal(clock) {
+				t.Errorf("got created_at %v and updated_at %v, want %v and %v", got.CreatedAt, got.UpdatedAt, now, clock)
+			}
+		})
+	}
+
+	// Leaves the caller's update unchanged
+	update := bson.D{{"$set", bson.M{"rating": int32(9)}}}
+	if err := teas.Update(ctx, id, update); err != nil {
+		t.Fatal(err)
+	}
+	if want := (bson.D{{"$set", bson.M{"rating": int32(9)}}}); !reflect.DeepEqual(update, want) {
+		t.Errorf("Update changed its argument to %v", update)
+	}
+
+	if err := teas.Update(ctx, bson.NewObjectID(), bson.D{{"$inc", bson.D{{"rating", 1}}}}); !errors.Is(err, mongo.ErrNoDocuments) {
+		t.Errorf("Update of a missing document returned %v, want mongo.ErrNoDocuments", err)
+	}
+	if err := teas.Update(ctx, id, bson.D{{"$set", 5}}); err == nil {
+		t.Error("Update with a $set that is not a document succeeded")
+	}
+}
+
+func TestUpsert(t *testing.T) {
+	_, coll := startServer(t)
+	ctx := context.Background()
+	clock := now
+	teas := New[Tea](coll, Options{SoftDelete: true, Timestamps: true, Now: func() time.Time { return clock }})
+
+	// Ignores the _id and created_at of the argument
+	callerID := bson.NewObjectID()
+	inserted, err := teas.Upsert(ctx, bson.D{{"type", "Matcha"}}, Tea{ID: callerID, Type: "Matcha", Rating: 9, CreatedAt: now.Add(-time.Hour)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !inserted {
+		t.Error("Upsert reported an update, want an insert")
+	}
+	list, err := teas.List(ctx, bson.D{{"type", "Matcha"}}, ListOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(list) != 1 {
+		t.Fatalf("got %d Matcha documents, want 1", len(list))
+	}
+	matcha := list[0]
+	if matcha.ID == callerID || matcha.Rating != 9 || !matcha.CreatedAt.Equal(now) || !matcha.UpdatedAt.Equal(now) {
+		t.Errorf("got %+v after the insert", matcha)
+	}
+
+	// Keeps the first created_at and restores a soft-deleted document
+	if err = teas.Delete(ctx, matcha.ID); err != nil {
+		t.Fatal(err)
+	}
+	clock = now.Add(time.Hour)
+	if inserted, err = teas.Upsert(ctx, bson.D{{"type", "Matcha"}}, Tea{Type: "Matcha", Rating: 10}); err != nil || inserted {
+		t.Fatalf("Upsert of an existing document returned %v, %v, want false, nil", inserted, err)
+	}
+	got, err := teas.Get(ctx, matcha.ID)
+	if err != nil {
+		t.Fatalf("Upsert did not restore the soft-deleted document: %v", err)
+	}
+	if got.Rating != 10 || !got.CreatedAt.Equal(now) || !got.UpdatedAt.Equal(clock) {
+		t.Errorf("got %+v after the update", got)
+	}
+	if n, err := coll.CountDocuments(ctx, bson.D{}); err != nil || n != 1 {
+		t.Errorf("collection holds %d documents (%v), want 1", n, err)
+	}
+}
+
+func TestDelete(t *testing.T) {
+	_, coll := startServer(t)
+	ctx := context.Background()
+
+	t.Run("hard", func(t *testing.T) {
+		teas := New[Tea](coll, Options{})
+		id, err := teas.Create(ctx, Tea{Type: "Assam"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err = teas.Delete(ctx, id); err != nil {
+			t.Fatal(err)
+		}
+		if err = coll.FindOne(ctx, bson.D{{"_id", id}}).Err(); !errors.Is(err, mongo.ErrNoDocuments) {
+			t.Errorf("the document remains after a hard delete: %v", err)
+		}
+		if err = teas.Delete(ctx, id); !errors.Is(err, mongo.ErrNoDocuments) {
+			t.Errorf("Delete of a missing document returned %v, want mongo.ErrNoDocuments", err)
+		}
+	})
+
+	t.Run("soft", func(t *testing.T) {
+		teas := New[Tea](coll, Options{SoftDelete: true, Now: func() time.Time { return now }})
+		id, err := teas.Create(ctx, Tea{Type: "Sencha"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err = teas.Delete(ctx, id); err != nil {
+			t.Fatal(err)
+		}
+		if at, ok := stored(t, coll, id)["deleted_at"].(bson.DateTime); !ok || !at.Time().Equal(now) {
+			t.Errorf("got deleted_at %v, want %v", stored(t, coll, id)["deleted_at"], now)
+		}
+		if _, err = teas.Get(ctx, id); !errors.Is(err, mongo.ErrNoDocuments) {
+			t.Errorf("Get after a soft delete returned %v, want mongo.ErrNoDocuments", err)
+		}
+		if err = teas.Delete(ctx, id); !errors.Is(err, mongo.ErrNoDocuments) {
+			t.Errorf("deleting twice returned %v, want mongo.ErrNoDocuments", err)
+		}
+	})
+}
@@ -0,0 +1,192 @@
+// Shows how retryable reads and writes recover from a closed connection by
+// using the Go driver
+//
+// This example uses the failCommand fail point, so it must run against a
+// local replica set member started with "--setParameter
+// enableTestCommands=1".
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"sync"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/event"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+)
+
+const appName = "retryableOperations"
+
+// start-command-log
+// Prints each insert and find command and counts the attempts for each
+// command name
+type commandLog struct {
+	mu       sync.Mutex
+	attempts map[string]int
+}
+
+func (l *commandLog) monitor() *event.CommandMonitor {
+	return &event.CommandMonitor{
+		Started: func(_ context.Context, e *event.CommandStartedEvent) {
+			if !l.watched(e.CommandName) {
+				return
+			}
+			l.mu.Lock()
+			l.attempts[e.CommandName]++
+			n := l.attempts[e.CommandName]
+			l.mu.Unlock()
+			fmt.Printf("  started %s attempt %d on connection %s\n", e.CommandName, n, e.ConnectionID)
+		},
+		Succeeded: func(_ context.Context, e *event.CommandSucceededEvent) {
+			if l.watched(e.CommandName) {
+				fmt.Printf("  %s succeeded after %v\n", e.CommandName, e.Duration)
+			}
+		},
+		Failed: func(_ context.Context, e *event.CommandFailedEvent) {
+			if l.watched(e.CommandName) {
+				fmt.Printf("  %s failed: %v\n", e.CommandName, e.Failure)
+			}
+		},
+	}
+}
+
+func (l *commandLog) watched(name string) bool {
+	return name == "insert" || name == "find"
+}
+
+// Returns the number of attempts for name since the last reset
+func (l *commandLog) count(name string) int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.attempts[name]
+}
+
+func (l *commandLog) reset() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.attempts = map[string]int{}
+}
+
+// end-command-log
+
+func main() {
+	var uri string
+	if uri = os.Getenv("MONGODB_URI"); uri == "" {
+		log.Fatal("You must set your 'MONGODB_URI' environment variable. See\n\t https://www.mongodb.com/docs/drivers/go/current/usage-examples/")
+	}
+
+	// Sets fail points from a separate client so that the command log only
+	// shows the operations under test
+	admin, err := mongo.Connect(options.Client().ApplyURI(uri))
+	if err != nil {
+		panic(err)
+	}
+	defer func() {
+		if err = admin.Disconnect(context.TODO()); err != nil {
+			panic(err)
+		}
+	}()
+
+	cmdLog := &commandLog{attempts: map[string]int{}}
+
+	// begin clients
+	// Retryable reads and writes are on by default
+	retrying, err := mongo.Connect(options.Client().ApplyURI(uri).
+		SetAppName(appName).
+		SetMonitor(cmdLog.monitor()))
+	if err != nil {
+		panic(err)
+	}
+	defer retrying.Disconnect(context.TODO())
+
+	nonRetrying, err := mongo.Connect(options.Client().ApplyURI(uri).
+		SetAppName(appName).
+		SetMonitor(cmdLog.monitor()).
+		SetRetryWrites(false).
+		SetRetryReads(false))
+	if err != nil {
+		panic(err)
+	}
+	defer nonRetrying.Disconnect(context.TODO())
+	// end clients
+
+	_ = retrying.Database("db").Collection("retries").Drop(context.TODO())
+
+	for _, c := range []struct {
+		name   string
+		client *mongo.Client
+		retry  bool
+	}{
+		{"Retries On", retrying, true},
+		{"Retries Off", nonRetrying, false},
+	} {
+		coll := c.client.Database("db").Collection("retries")
+
+		fmt.Printf("\n%s - InsertOne:\n", c.name)
+		{
+			cmdLog.reset()
+
+			// Closes the connection the next time the server receives an
+			// insert command from this program
+			// begin insert with fail point
+			setCloseConnection(admin, "insert")
+			_, err := coll.InsertOne(context.TODO(), bson.D{{"client", c.name}})
+			fmt.Printf("InsertOne returned error: %v\n", err)
+			// end insert with fail point
+
+			check(c.name+" InsertOne", c.retry, err, cmdLog.count("insert"))
+		}
+
+		fmt.Printf("\n%s - Find:\n", c.name)
+		{
+			cmdLog.reset()
+
+			// begin find with fail point
+			setCloseConnection(admin, "find")
+			cursor, err := coll.Find(context.TODO(), bson.D{})
+			if err == nil {
+				err = cursor.Close(context.TODO())
+			}
+			fmt.Printf("Find returned error: %v\n", err)
+			// end find with fail point
+
+			check(c.name+" Find", c.retry, err, cmdLog.count("find"))
+		}
+	}
+}
+
+// start-fail-point
+// Makes the server close the connection once, the next time it receives
+// command from a client with this program's app name
+func setCloseConnection(admin *mongo.Client, command string) {
+	cmd := bson.D{
+		{"configureFailPoint", "failCommand"},
+		{"mode", bson.D{{"times", 1}}},
+		{"data", bson.D{
+			{"failCommands", bson.A{command}},
+			{"closeConnection", true},
+			{"appName", appName},
+		}},
+	}
+	err := admin.Database("admin").RunCommand(context.TODO(), cmd).Err()
+	if err != nil {
+		log.Fatalf("failed to set the fail point, is enableTestCommands set? %v", err)
+	}
+}
+
+// end-fail-point
+
+// Exits unless a retrying client succeeded on its second attempt or a
+// non-retrying client failed with a network error on its only attempt
+func check(name string, retry bool, err error, attempts int) {
+	switch {
+	case retry && (err != nil || attempts != 2):
+		log.Fatalf("%s: got error %v after %d attempts, want success after 2 attempts", name, err, attempts)
+	case !retry && (!mongo.IsNetworkError(err) || attempts != 1):
+		log.Fatalf("%s: got error %v after %d attempts, want a network error after 1 attempt", name, err, attempts)
+	}
+}
@@ -0,0 +1,198 @@
+// Package searchindex waits for Atlas Search and Atlas Vector Search indexes
+// to become queryable, to rebuild after an update, and to be dropped. Each
+// wait polls the $listSearchIndexes stage with exponential backoff.
+//
+// Example:
+//
+//	view := coll.SearchIndexes()
+//	if err := view.UpdateOne(ctx, "plot_search", definition); err != nil {
+//		return err
+//	}
+//	status, err := searchindex.WaitForDefinition(ctx, view, "plot_search", definition, 10*time.Minute)
+package searchindex
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"time"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+)
+
+// Holds the fields of a $listSearchIndexes result that describe whether an
+// index can serve queries and which definition it serves them with
+type Status struct {
+	Name                    string            `bson:"name"`
+	Status                  string            `bson:"status"`
+	Queryable               bool              `bson:"queryable"`
+	LatestDefinition        bson.Raw          `bson:"latestDefinition"`
+	LatestDefinitionVersion DefinitionVersion `bson:"latestDefinitionVersion"`
+	StatusDetail            []HostStatus      `bson:"statusDetail"`
+}
+
+// Identifies one definition of an index. Each update increments Version.
+type DefinitionVersion struct {
+	Version int64 `bson:"version"`
+}
+
+// Describes the index on one search host. The main index serves queries
+// while a newer definition builds as a staged index.
+type HostStatus struct {
+	Hostname  string `bson:"hostname"`
+	MainIndex struct {
+		Status            string            `bson:"status"`
+		Queryable         bool              `bson:"queryable"`
+		DefinitionVersion DefinitionVersion `bson:"definitionVersion"`
+	} `bson:"mainIndex"`
+}
+
+// Reports whether every host serves queries with the latest definition. A
+// deployment that does not report statusDetail is treated as serving it.
+func (s Status) ServesLatestDefinition() bool {
+	for _, host := range s.StatusDetail {
+		if host.MainIndex.DefinitionVersion.Version != s.LatestDefinitionVersion.Version {
+			return false
+		}
+	}
+	return true
+}
+
+// The first and longest delays between polls. Tests can shorten them.
+var (
+	MinPollDelay = time.Second
+	MaxPollDelay = 30 * time.Second
+)
+
+// Polls the named search index until ready reports true for its status.
+// Returns an error if the index does not exist, its build fails, or timeout
+// elapses.
+func Wait(
+	ctx context.Context,
+	view mongo.SearchIndexView,
+	name string,
+	timeout time.Duration,
+	ready func(Status) bool,
+) (Status, error) {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	var last Status
+	err := poll(ctx, func() (bool, error) {
+		status, found, err := Get(ctx, view, name)
+		if err != nil {
+			return false, err
+		}
+		if !found {
+			return false, fmt.Errorf("search index %q does not exist", name)
+		}
+		if status.Status == "FAILED" {
+			return false, fmt.Errorf("search index %q failed to build", name)
+		}
+		last = status
+		return ready(status), nil
+	})
+	if errors.Is(err, context.DeadlineExceeded) {
+		return last, fmt.Errorf("timed out waiting for search index %q, last status %q: %w", name, last.Status, err)
+	}
+	return last, err
+}
+
+// Polls the named search index until it can serve queries
+func WaitForQueryable(ctx context.Context, view mongo.SearchIndexView, name string, timeout time.Duration) (Status, error) {
+	return Wait(ctx, view, name, timeout, func(s Status) bool {
+		return s.Queryable
+	})
+}
+
+// Polls the named search index until it serves queries with definition,
+// such as after a create or an update. The index can report "READY" for an
+// earlier definition while the new one builds, so this compares the latest
+// definition with definition and checks that every host serves it. The
+// comparison ignores the order of fields and the numeric types of values.
+func WaitForDefinition(
+	ctx context.Context,
+	view mongo.SearchIndexView,
+	name string,
+	definition interface{},
+	timeout time.Duration,
+) (Status, error) {
+	want, err := normalize(definition)
+	if err != nil {
+		return Status{}, fmt.Errorf("invalid definition: %w", err)
+	}
+
+	return Wait(ctx, view, name, timeout, func(s Status) bool {
+		if s.Status != "READY" || !s.Queryable || s.LatestDefinition == nil {
+			return false
+		}
+		got, err := normalize(s.LatestDefinition)
+		return err == nil && reflect.DeepEqual(got, want) && s.ServesLatestDefinition()
+	})
+}
+
+// Polls until the named search index no longer appears in the list of
+// search indexes
+func WaitForDrop(ctx context.Context, view mongo.SearchIndexView, name string, timeout time.Duration) error {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	return poll(ctx, func() (bool, error) {
+		_, found, err := Get(ctx, view, name)
+		return !found, err
+	})
+}
+
+// Returns the status of the named search index and whether it exists
+func Get(ctx context.Context, view mongo.SearchIndexView, name string) (Status, bool, error) {
+	cursor, err := view.List(ctx, options.SearchIndexes().SetName(name))
+	if err != nil {
+		return Status{}, false, err
+	}
+
+	var results []Status
+	if err = cursor.All(ctx, &results); err != nil {
+		return Status{}, false, err
+	}
+	if len(results) == 0 {
+		return Status{}, false, nil
+	}
+	return results[0], true, nil
+}
+
+// Converts a definition to JSON values, in which documents are maps and
+// every number is a float64
+func normalize(definition interface{}) (interface{}, error) {
+	data, err := bson.MarshalExtJSON(definition, false, false)
+	if err != nil {
+		return nil, err
+	}
+	var v interface{}
+	err = json.Unmarshal(data, &v)
+	return v, err
+}
+
+// Calls check with exponential backoff until it returns true or an error,
+// or until ctx is done
+func poll(ctx context.Context, check func() (bool, error)) error {
+	delay := MinPollDelay
+
+	for {
+		done, err := check()
+		if err != nil || done {
+			return err
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(delay):
+		}
+
+		delay = min(delay*2, MaxPollDelay)
+	}
+}
@@ -0,0 +1,191 @@
+// The tests use package searchindex_test because the searchindextest
+// stand-in imports package searchindex
+package searchindex_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+
+	"github.com/mongodb/docs-golang/source/includes/fundamentals/code-snippets/searchindex"
+	"github.com/mongodb/docs-golang/source/includes/fundamentals/code-snippets/searchindex/searchindextest"
+)
+
+const name = "plot_search"
+
+var (
+	plotOnly = bson.D{{"mappings", bson.D{
+		{"dynamic", false},
+		{"fields", bson.D{{"plot", bson.D{{"type", "string"}}}}},
+	}}}
+	plotAndTitle = bson.D{{"mappings", bson.D{
+		{"dynamic", false},
+		{"fields", bson.D{
+			{"plot", bson.D{{"type", "string"}}},
+			{"title", bson.D{{"type", "string"}}},
+		}},
+	}}}
+)
+
+// Starts the stand-in and creates an index with the plotOnly definition
+func startWithIndex(t *testing.T) (*searchindextest.Indexes, mongo.SearchIndexView) {
+	t.Helper()
+	indexes, client := searchindextest.Start(t)
+	view := client.Database("sample_mflix").Collection("movies").SearchIndexes()
+	model := mongo.SearchIndexModel{
+		Definition: plotOnly,
+		Options:    options.SearchIndexes().SetName(name).SetType("search"),
+	}
+	if _, err := view.CreateOne(context.Background(), model); err != nil {
+		t.Fatal(err)
+	}
+	return indexes, view
+}
+
+// Creates, updates, and drops an index, and checks that each wait returns
+// only after the index reaches the expected state
+func TestLifecycle(t *testing.T) {
+	indexes, view := startWithIndex(t)
+	ctx := context.Background()
+
+	status, err := searchindex.WaitForQueryable(ctx, view, name, time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !status.Queryable || status.Status != "READY" || !indexes.ScriptDone(name) {
+		t.Fatalf("create wait returned %+v before the index was built", status)
+	}
+
+	// The index reports "READY" with the new definition before the rebuild
+	// starts, and the wait must not return then
+	if err = view.UpdateOne(ctx, name, plotAndTitle); err != nil {
+		t.Fatal(err)
+	}
+	status, err = searchindex.WaitForDefinition(ctx, view, name, plotAndTitle, time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !indexes.ScriptDone(name) || status.LatestDefinitionVersion.Version != 2 || !status.ServesLatestDefinition() {
+		t.Fatalf("update wait returned %+v before the index was rebuilt", status)
+	}
+
+	if err = view.DropOne(ctx, name); err != nil {
+		t.Fatal(err)
+	}
+	if err = searchindex.WaitForDrop(ctx, view, name, time.Minute); err != nil {
+		t.Fatal(err)
+	}
+	if _, found, err := searchindex.Get(ctx, view, name); err != nil || found {
+		t.Errorf("index found %v with error %v after the drop wait", found, err)
+	}
+}
+
+// Returns when a rebuild finishes between two polls, so that the wait never
+// sees the index leave the "READY" status
+func TestWaitForDefinitionFastRebuild(t *testing.T) {
+	indexes, view := startWithIndex(t)
+	ctx := context.Background()
+	indexes.SetScript(name, "READY")
+	if _, err := searchindex.WaitForQueryable(ctx, view, name, time.Minute); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := view.UpdateOne(ctx, name, plotAndTitle); err != nil {
+		t.Fatal(err)
+	}
+	indexes.SetScript(name, "READY")
+	if _, err := searchindex.WaitForDefinition(ctx, view, name, plotAndTitle, time.Second); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// Compares definitions regardless of field order and numeric types, and
+// keeps waiting while the index has a different definition
+func TestWaitForDefinitionComparison(t *testing.T) {
+	indexes, view := startWithIndex(t)
+	ctx := context.Background()
+	vector := bson.D{{"fields", bson.A{bson.D{
+		{"type", "vector"},
+		{"path", "plot_embedding"},
+		{"numDimensions", int32(256)},
+		{"similarity", "dotProduct"},
+	}}}}
+	if err := view.UpdateOne(ctx, name, vector); err != nil {
+		t.Fatal(err)
+	}
+
+	same := bson.M{"fields": bson.A{bson.M{
+		"similarity":    "dotProduct",
+		"numDimensions": int64(256),
+		"path":          "plot_embedding",
+		"type":          "vector",
+	}}}
+	if _, err := searchindex.WaitForDefinition(ctx, view, name, same, time.Minute); err != nil {
+		t.Fatal(err)
+	}
+
+	indexes.SetScript(name, "READY")
+	_, err := searchindex.WaitForDefinition(ctx, view, name, plotOnly, 50*time.Millisecond)
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("got error %v for another definition, want a timeout", err)
+	}
+}
+
+func TestWaitFailed(t *testing.T) {
+	indexes, view := startWithIndex(t)
+	indexes.SetScript(name, "PENDING", "FAILED")
+
+	_, err := searchindex.WaitForQueryable(context.Background(), view, name, time.Minute)
+	if err == nil {
+		t.Error("wait succeeded for an index that failed to build")
+	}
+}
+
+func TestWaitTimeout(t *testing.T) {
+	indexes, view := startWithIndex(t)
+	indexes.SetScript(name, "PENDING")
+
+	_, err := searchindex.WaitForQueryable(context.Background(), view, name, 50*time.Millisecond)
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("got error %v, want a timeout", err)
+	}
+}
+
+func TestWaitMissing(t *testing.T) {
+	_, client := searchindextest.Start(t)
+	view := client.Database("sample_mflix").Collection("movies").SearchIndexes()
+	_, err := searchindex.WaitForDefinition(context.Background(), view, name, plotOnly, time.Minute)
+	if err == nil {
+		t.Error("wait succeeded for an index that does not exist")
+	}
+}
+
+func TestServesLatestDefinition(t *testing.T) {
+	host := func(version int64) searchindex.HostStatus {
+		var h searchindex.HostStatus
+		h.MainIndex.DefinitionVersion.Version = version
+		return h
+	}
+	latest := searchindex.DefinitionVersion{Version: 3}
+
+	cases := []struct {
+		name  string
+		hosts []searchindex.HostStatus
+		want  bool
+	}{
+		{"no status detail", nil, true},
+		{"every host current", []searchindex.HostStatus{host(3), host(3)}, true},
+		{"one host behind", []searchindex.HostStatus{host(3), host(2)}, false},
+	}
+	for _, c := range cases {
+		s := searchindex.Status{LatestDefinitionVersion: latest, StatusDetail: c.hosts}
+		if got := s.ServesLatestDefinition(); got != c.want {
+			t.Errorf("%s: got %v, want %v", c.name, got, c.want)
+		}
+	}
+}
@@ -0,0 +1,225 @@
+// Package searchindextest answers the Atlas Search index commands on the
+// fake server in tools/fakeserver, so that code that creates, updates, and
+// drops search indexes can be tested without an Atlas cluster.
+//
+// Each index moves through a scripted list of statuses, one for each time it
+// is listed. Like Atlas, an updated index reports its new definition at once
+// but keeps serving queries with the old definition until its rebuild
+// finishes, which is when it reports the last scripted status.
+//
+// Example:
+//
+//	indexes, client := searchindextest.Start(t)
+//	view := client.Database("sample_mflix").Collection("movies").SearchIndexes()
+//	indexes.SetScript("plot_search", "PENDING", "FAILED")
+package searchindextest
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+
+	"github.com/mongodb/docs-golang/source/includes/fundamentals/code-snippets/searchindex"
+	"github.com/mongodb/docs-golang/source/includes/fundamentals/code-snippets/tools/fakeserver"
+)
+
+// Holds the search indexes of every collection on one fake server
+type Indexes struct {
+	uri     string
+	mu      sync.Mutex
+	indexes map[string]*index
+}
+
+type index struct {
+	status     string
+	definition interface{}
+	// The latest definition version, and the version that serves queries,
+	// which is 0 until the first build finishes
+	version     int64
+	mainVersion int64
+	// The statuses to report next, after which the index keeps its status
+	// or, if status is "DELETING", disappears
+	script []string
+}
+
+// Starts a fake server that answers the search index commands and returns a
+// client connected to it. The server answers only the $listSearchIndexes
+// stage of the aggregate command. Start also shortens the delays between
+// polls in package searchindex until the test ends.
+func Start(t *testing.T) (*Indexes, *mongo.Client) {
+	t.Helper()
+
+	minDelay, maxDelay := searchindex.MinPollDelay, searchindex.MaxPollDelay
+	searchindex.MinPollDelay, searchindex.MaxPollDelay = time.Millisecond, time.Millisecond
+	t.Cleanup(func() { searchindex.MinPollDelay, searchindex.MaxPollDelay = minDelay, maxDelay })
+
+	srv, err := fakeserver.Start()
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { srv.Close() })
+
+	s := &Indexes{uri: srv.URI(), indexes: map[string]*index{}}
+	srv.Handle("createSearchIndexes", s.create)
+	srv.Handle("updateSearchIndex", s.update)
+	srv.Handle("dropSearchIndex", s.drop)
+	srv.Handle("aggregate", s.list)
+
+	client, err := mongo.Connect(options.Client().ApplyURI(srv.URI()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { client.Disconnect(context.Background()) })
+	return s, client
+}
+
+// Returns the connection string of the fake server, for programs that
+// connect on their own
+func (s *Indexes) URI() string {
+	return s.uri
+}
+
+// Replaces the statuses that the named index reports next
+func (s *Indexes) SetScript(name string, script ...string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.indexes[name].script = script
+}
+
+// Reports whether the named index has reported every scripted status
+func (s *Indexes) ScriptDone(name string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.indexes[name].script) == 0
+}
+
+// Returns the latest definition version of the named index, or 0 if the
+// index does not exist
+func (s *Indexes) Version(name string) int64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if index, ok := s.indexes[name]; ok {
+		return index.version
+	}
+	return 0
+}
+
+func (s *Indexes) create(db string, cmd bson.D) bson.D {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var created bson.A
+	for _, v := range field(cmd, "indexes").(bson.A) {
+		spec := v.(bson.D)
+		name, _ := field(spec, "name").(string)
+		if name == "" {
+			name = "default"
+		}
+		if _, exists := s.indexes[name]; exists {
+			return fakeserver.ErrorReply(68, "IndexAlreadyExists", fmt.Sprintf("Index %s already exists", name))
+		}
+		s.indexes[name] = &index{
+			status:     "PENDING",
+			definition: field(spec, "definition"),
+			version:    1,
+			script:     []string{"PENDING", "BUILDING", "READY"},
+		}
+		created = append(created, bson.D{{"id", name}, {"name", name}})
+	}
+	return bson.D{{"ok", 1}, {"indexesCreated", created}}
+}
+
+// Reports the new definition at once, and the old "READY" status for two
+// polls before the rebuild starts
+func (s *Indexes) update(db string, cmd bson.D) bson.D {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	index, ok := s.indexes[field(cmd, "name").(string)]
+	if !ok {
+		return fakeserver.ErrorReply(27, "IndexNotFound", "search index not found")
+	}
+	index.definition = field(cmd, "definition")
+	index.version++
+	index.script = []string{"READY", "READY", "PENDING", "BUILDING", "BUILDING", "READY"}
+	return bson.D{{"ok", 1}}
+}
+
+func (s *Indexes) drop(db string, cmd bson.D) bson.D {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	index, ok := s.indexes[field(cmd, "name").(string)]
+	if !ok {
+		return fakeserver.ErrorReply(27, "IndexNotFound", "search index not found")
+	}
+	index.script = []string{"DELETING", "DELETING"}
+	return bson.D{{"ok", 1}}
+}
+
+// Answers the $listSearchIndexes stage that SearchIndexView.List sends
+func (s *Indexes) list(db string, cmd bson.D) bson.D {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	ns := db + "." + field(cmd, "aggregate").(string)
+	pipeline := field(cmd, "pipeline").(bson.A)
+	if len(pipeline) != 1 || pipeline[0].(bson.D)[0].Key != "$listSearchIndexes" {
+		return fakeserver.ErrorReply(2, "BadValue", "the stand-in answers only $listSearchIndexes")
+	}
+	name, _ := field(pipeline[0].(bson.D)[0].Value.(bson.D), "name").(string)
+
+	index, ok := s.indexes[name]
+	if !ok {
+		return fakeserver.CursorReply(ns, nil)
+	}
+	if len(index.script) > 0 {
+		index.status, index.script = index.script[0], index.script[1:]
+		if len(index.script) == 0 && index.status == "READY" {
+			index.mainVersion = index.version
+		}
+	}
+	if len(index.script) == 0 && index.status == "DELETING" {
+		delete(s.indexes, name)
+		return fakeserver.CursorReply(ns, nil)
+	}
+
+	queryable := index.mainVersion > 0
+	mainStatus := index.status
+	if queryable {
+		mainStatus = "READY"
+	}
+	return fakeserver.CursorReply(ns, []bson.D{{
+		{"name", name},
+		{"status", index.status},
+		{"queryable", queryable},
+		{"latestDefinitionVersion", bson.D{{"version", index.version}}},
+		{"latestDefinition", index.definition},
+		{"statusDetail", bson.A{bson.D{
+			{"hostname", "atlas-search-0"},
+			{"status", index.status},
+			{"queryable", queryable},
+			{"mainIndex", bson.D{
+				{"status", mainStatus},
+				{"queryable", queryable},
+				{"definitionVersion", bson.D{{"version", index.mainVersion}}},
+			}},
+		}}},
+	}})
+}
+
+// Returns the value of key in d, or nil if d does not have key
+func field(d bson.D, key string) interface{} {
+	for _, e := range d {
+		if e.Key == key {
+			return e.Value
+		}
+	}
+	return nil
+}
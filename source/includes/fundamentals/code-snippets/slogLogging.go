@@ -0,0 +1,145 @@
+// Sends driver log messages to a log/slog logger by using the Go driver
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"log"
+	"log/slog"
+	"os"
+
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+)
+
+// start-slog-sink
+// Implements options.LogSink by writing each driver message to an
+// *slog.Logger. The key/value pairs become slog attributes, and a
+// "component" attribute names the part of the driver that logged the
+// message.
+type SlogSink struct {
+	logger *slog.Logger
+}
+
+func NewSlogSink(logger *slog.Logger) *SlogSink {
+	return &SlogSink{logger: logger}
+}
+
+// Receives 0 for the driver's info level and 1 for its debug level
+func (s *SlogSink) Info(level int, msg string, keysAndValues ...interface{}) {
+	slogLevel := slog.LevelInfo
+	if options.LogLevel(level+1) >= options.LogLevelDebug {
+		slogLevel = slog.LevelDebug
+	}
+	s.log(slogLevel, msg, keysAndValues, nil)
+}
+
+func (s *SlogSink) Error(err error, msg string, keysAndValues ...interface{}) {
+	s.log(slog.LevelError, msg, keysAndValues, err)
+}
+
+func (s *SlogSink) log(level slog.Level, msg string, keysAndValues []interface{}, err error) {
+	ctx := context.Background()
+	if !s.logger.Enabled(ctx, level) {
+		return
+	}
+
+	attrs := make([]slog.Attr, 0, len(keysAndValues)/2+2)
+	attrs = append(attrs, slog.String("component", component(keysAndValues)))
+	if err != nil {
+		attrs = append(attrs, slog.Any("error", err))
+	}
+
+	for i := 0; i < len(keysAndValues); i += 2 {
+		key, ok := keysAndValues[i].(string)
+		if !ok {
+			key = fmt.Sprint(keysAndValues[i])
+		}
+		// slog already records the message
+		if key == "message" {
+			continue
+		}
+
+		var value interface{} = "(missing)"
+		if i+1 < len(keysAndValues) {
+			value = keysAndValues[i+1]
+		}
+		attrs = append(attrs, slog.Any(key, value))
+	}
+
+	s.logger.LogAttrs(ctx, level, msg, attrs...)
+}
+
+// Infers the driver component from the keys of a message, because the
+// driver does not pass the component to the sink
+func component(keysAndValues []interface{}) string {
+	keys := map[string]bool{}
+	for i := 0; i < len(keysAndValues); i += 2 {
+		if key, ok := keysAndValues[i].(string); ok {
+			keys[key] = true
+		}
+	}
+
+	switch {
+	case keys["commandName"]:
+		return "command"
+	case keys["selector"]:
+		return "serverSelection"
+	case keys["topologyId"]:
+		return "topology"
+	case keys["serverHost"]:
+		return "connection"
+	default:
+		return "unknown"
+	}
+}
+
+// end-slog-sink
+
+func main() {
+	var uri string
+	if uri = os.Getenv("MONGODB_URI"); uri == "" {
+		log.Fatal("You must set your 'MONGODB_URI' environment variable. See\n\t https://www.mongodb.com/docs/drivers/go/current/usage-examples/")
+	}
+
+	fmt.Println("Driver Messages:")
+	{
+		// begin set slog sink
+		var buf bytes.Buffer
+		handler := slog.NewJSONHandler(&buf, &slog.HandlerOptions{Level: slog.LevelDebug})
+		sink := NewSlogSink(slog.New(handler))
+
+		loggerOptions := options.
+			Logger().
+			SetSink(sink).
+			SetComponentLevel(options.LogComponentCommand, options.LogLevelDebug).
+			SetComponentLevel(options.LogComponentConnection, options.LogLevelDebug)
+
+		clientOptions := options.
+			Client().
+			ApplyURI(uri).
+			SetLoggerOptions(loggerOptions)
+		// end set slog sink
+
+		client, err := mongo.Connect(clientOptions)
+		if err != nil {
+			panic(err)
+		}
+
+		type Item struct {
+			Name string
+		}
+
+		coll := client.Database("db").Collection("testColl")
+		if _, err = coll.InsertOne(context.TODO(), Item{Name: "grapefruit"}); err != nil {
+			panic(err)
+		}
+		if err = client.Disconnect(context.TODO()); err != nil {
+			panic(err)
+		}
+
+		// Prints the JSON records that the insert produced
+		fmt.Print(buf.String())
+	}
+}
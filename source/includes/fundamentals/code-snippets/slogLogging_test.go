@@ -0,0 +1,157 @@
+// Tests the slog sink with golden records and with the messages that the
+// driver logs while it talks to the fake server. Run with:
+//
+//	go test slogLogging.go slogLogging_test.go
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"strings"
+	"testing"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+
+	"github.com/mongodb/docs-golang/source/includes/fundamentals/code-snippets/tools/fakeserver"
+)
+
+// Returns a sink that writes JSON records without a time to buf
+func newTestSink(buf *bytes.Buffer, level slog.Level) *SlogSink {
+	handler := slog.NewJSONHandler(buf, &slog.HandlerOptions{
+		Level: level,
+		ReplaceAttr: func(groups []string, a slog.Attr) slog.Attr {
+			if len(groups) == 0 && a.Key == slog.TimeKey {
+				return slog.Attr{}
+			}
+			return a
+		},
+	})
+	return NewSlogSink(slog.New(handler))
+}
+
+// Passes messages in the form that the driver logs them to the sink and
+// compares the output with golden records
+func TestSlogSinkGolden(t *testing.T) {
+	var buf bytes.Buffer
+	sink := newTestSink(&buf, slog.LevelDebug)
+
+	sink.Info(1, "Command started",
+		"commandName", "insert",
+		"databaseName", "db",
+		"driverConnectionId", int64(1),
+		"message", "Command started",
+		"operationId", int32(0),
+		"requestId", int64(12),
+		"serverHost", "localhost",
+		"command", `{"insert": "testColl"}`,
+		"serverPort", int64(27017),
+	)
+	sink.Info(1, "Connection checked out",
+		"serverHost", "localhost",
+		"serverPort", int64(27017),
+		"message", "Connection checked out",
+		"driverConnectionId", int64(1),
+	)
+	sink.Info(0, "Connection pool closed",
+		"serverHost", "localhost",
+		"serverPort", int64(27017),
+	)
+	sink.Error(errors.New("connection refused"), "Connection checkout failed",
+		"serverHost", "localhost",
+		"reason", "An error occurred while trying to establish a new connection",
+	)
+	sink.Info(0, "Odd pair", "topologyId", "1", "dangling")
+
+	golden := []string{
+		`{"level":"DEBUG","msg":"Command started","component":"command","commandName":"insert","databaseName":"db",` +
+			`"driverConnectionId":1,"operationId":0,"requestId":12,"serverHost":"localhost",` +
+			`"command":"{\"insert\": \"testColl\"}","serverPort":27017}`,
+		`{"level":"DEBUG","msg":"Connection checked out","component":"connection","serverHost":"localhost",` +
+			`"serverPort":27017,"driverConnectionId":1}`,
+		`{"level":"INFO","msg":"Connection pool closed","component":"connection","serverHost":"localhost","serverPort":27017}`,
+		`{"level":"ERROR","msg":"Connection checkout failed","component":"connection","error":"connection refused",` +
+			`"serverHost":"localhost","reason":"An error occurred while trying to establish a new connection"}`,
+		`{"level":"INFO","msg":"Odd pair","component":"topology","topologyId":"1","dangling":"(missing)"}`,
+	}
+
+	got := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(got) != len(golden) {
+		t.Fatalf("got %d records, want %d:\n%s", len(got), len(golden), buf.String())
+	}
+	for i := range golden {
+		if got[i] != golden[i] {
+			t.Errorf("record %d differs from golden output\ngot:  %s\nwant: %s", i, got[i], golden[i])
+		}
+	}
+}
+
+// Checks that the sink maps the driver's debug level to slog.LevelDebug, so
+// that a handler at the info level drops debug messages
+func TestSlogSinkLevels(t *testing.T) {
+	var buf bytes.Buffer
+	sink := newTestSink(&buf, slog.LevelInfo)
+
+	sink.Info(1, "Command started", "commandName", "ping")
+	sink.Info(0, "Connection pool created", "serverHost", "localhost")
+
+	got := strings.TrimSpace(buf.String())
+	want := `{"level":"INFO","msg":"Connection pool created","component":"connection","serverHost":"localhost"}`
+	if got != want {
+		t.Errorf("got %s, want %s", got, want)
+	}
+}
+
+// Runs an insert against the fake server and checks that the driver's
+// messages arrive as structured command and connection records
+func TestDriverMessages(t *testing.T) {
+	srv, err := fakeserver.Start()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer srv.Close()
+
+	var buf bytes.Buffer
+	loggerOptions := options.
+		Logger().
+		SetSink(newTestSink(&buf, slog.LevelDebug)).
+		SetComponentLevel(options.LogComponentCommand, options.LogLevelDebug).
+		SetComponentLevel(options.LogComponentConnection, options.LogLevelDebug)
+
+	client, err := mongo.Connect(options.Client().ApplyURI(srv.URI()).SetLoggerOptions(loggerOptions))
+	if err != nil {
+		t.Fatal(err)
+	}
+	coll := client.Database("db").Collection("testColl")
+	if _, err = coll.InsertOne(context.Background(), bson.D{{"name", "grapefruit"}}); err != nil {
+		t.Fatal(err)
+	}
+	if err = client.Disconnect(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	found := map[string]bool{}
+	scanner := bufio.NewScanner(&buf)
+	scanner.Buffer(nil, 1<<20)
+	for scanner.Scan() {
+		var record map[string]interface{}
+		if err := json.Unmarshal(scanner.Bytes(), &record); err != nil {
+			t.Fatalf("slog wrote a line that is not JSON: %v", err)
+		}
+
+		switch {
+		case record["component"] == "command" && record["commandName"] == "insert":
+			found["command"] = true
+		case record["component"] == "connection" && record["driverConnectionId"] != nil:
+			found["connection"] = true
+		}
+	}
+	if !found["command"] || !found["connection"] {
+		t.Errorf("missing structured records, found %v", found)
+	}
+}
@@ -0,0 +1,231 @@
+// Reads a point-in-time view of several collections by using a snapshot
+// session with the Go driver
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"math/rand"
+	"os"
+	"sync"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+)
+
+// start-structs
+type Course struct {
+	Title      string
+	Enrollment int32
+}
+
+type Book struct {
+	Title  string
+	Author string
+	Length int32
+}
+
+// end-structs
+
+// start-report-struct
+type Report struct {
+	EnrollmentFromFind      int32
+	EnrollmentFromAggregate int32
+	BooksFromFind           int32
+	BooksFromAggregate      int32
+}
+
+// end-report-struct
+
+func main() {
+	var uri string
+	if uri = os.Getenv("MONGODB_URI"); uri == "" {
+		log.Fatal("You must set your 'MONGODB_URI' environment variable. See\n\t https://www.mongodb.com/docs/drivers/go/current/usage-examples/")
+	}
+
+	client, err := mongo.Connect(options.Client().ApplyURI(uri))
+	if err != nil {
+		panic(err)
+	}
+	defer func() {
+		if err = client.Disconnect(context.TODO()); err != nil {
+			panic(err)
+		}
+	}()
+
+	// begin insertDocs
+	courseColl := client.Database("db").Collection("courses")
+	bookColl := client.Database("db").Collection("books")
+
+	courses := []interface{}{
+		Course{Title: "World Fiction", Enrollment: 35},
+		Course{Title: "Abstract Algebra", Enrollment: 60},
+		Course{Title: "Modern Poetry", Enrollment: 12},
+		Course{Title: "Plate Tectonics", Enrollment: 45},
+	}
+	books := []interface{}{
+		Book{Title: "My Brilliant Friend", Author: "Elena Ferrante", Length: 331},
+		Book{Title: "Lucy", Author: "Jamaica Kincaid", Length: 103},
+	}
+
+	if _, err = courseColl.InsertMany(context.TODO(), courses); err != nil {
+		panic(err)
+	}
+	if _, err = bookColl.InsertMany(context.TODO(), books); err != nil {
+		panic(err)
+	}
+	// end insertDocs
+
+	// Each writer transaction moves one student between courses, so the
+	// total enrollment never changes
+	initial := totalEnrollment(context.TODO(), courseColl)
+
+	ctx, cancel := context.WithCancel(context.TODO())
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		mutate(ctx, client, courseColl, bookColl)
+	}()
+
+	for i := 0; i < 10; i++ {
+		report := snapshotReport(client, courseColl, bookColl)
+		fmt.Printf("Report %d: %+v\n", i, report)
+
+		if report.EnrollmentFromFind != report.EnrollmentFromAggregate ||
+			report.BooksFromFind != report.BooksFromAggregate {
+			log.Fatalf("Report %d is not internally consistent: %+v", i, report)
+		}
+		if report.EnrollmentFromFind != initial {
+			log.Fatalf("Report %d observed a partial transfer: total enrollment %d, expected %d",
+				i, report.EnrollmentFromFind, initial)
+		}
+	}
+
+	cancel()
+	wg.Wait()
+}
+
+// Builds a report from several reads that all observe the same snapshot
+func snapshotReport(client *mongo.Client, courseColl, bookColl *mongo.Collection) Report {
+	// start-snapshot-session
+	// Starts a session in which every read uses the same point in time
+	sessOpts := options.Session().SetSnapshot(true)
+	session, err := client.StartSession(sessOpts)
+	if err != nil {
+		panic(err)
+	}
+	defer session.EndSession(context.TODO())
+
+	ctx := mongo.NewSessionContext(context.TODO(), session)
+
+	var report Report
+
+	// Sums the enrollment of each course on the client
+	cursor, err := courseColl.Find(ctx, bson.D{})
+	if err != nil {
+		panic(err)
+	}
+	var courses []Course
+	if err = cursor.All(ctx, &courses); err != nil {
+		panic(err)
+	}
+	for _, course := range courses {
+		report.EnrollmentFromFind += course.Enrollment
+	}
+
+	// Sums the enrollment of each course on the server
+	report.EnrollmentFromAggregate = totalEnrollment(ctx, courseColl)
+
+	// Counts the books on the client and on the server
+	cursor, err = bookColl.Find(ctx, bson.D{})
+	if err != nil {
+		panic(err)
+	}
+	var books []Book
+	if err = cursor.All(ctx, &books); err != nil {
+		panic(err)
+	}
+	report.BooksFromFind = int32(len(books))
+
+	countStage := bson.D{{"$count", "total"}}
+	cursor, err = bookColl.Aggregate(ctx, mongo.Pipeline{countStage})
+	if err != nil {
+		panic(err)
+	}
+	var counts []struct{ Total int32 }
+	if err = cursor.All(ctx, &counts); err != nil {
+		panic(err)
+	}
+	if len(counts) > 0 {
+		report.BooksFromAggregate = counts[0].Total
+	}
+	// end-snapshot-session
+
+	return report
+}
+
+// Returns the sum of the "enrollment" field across all courses
+func totalEnrollment(ctx context.Context, coll *mongo.Collection) int32 {
+	groupStage := bson.D{
+		{"$group", bson.D{
+			{"_id", nil},
+			{"total", bson.D{{"$sum", "$enrollment"}}},
+		}}}
+
+	cursor, err := coll.Aggregate(ctx, mongo.Pipeline{groupStage})
+	if err != nil {
+		panic(err)
+	}
+
+	var results []struct{ Total int32 }
+	if err = cursor.All(ctx, &results); err != nil {
+		panic(err)
+	}
+	if len(results) == 0 {
+		return 0
+	}
+	return results[0].Total
+}
+
+// Moves students between courses and adds books in transactions until ctx
+// is canceled
+func mutate(ctx context.Context, client *mongo.Client, courseColl, bookColl *mongo.Collection) {
+	titles := []string{"World Fiction", "Abstract Algebra", "Modern Poetry", "Plate Tectonics"}
+
+	session, err := client.StartSession()
+	if err != nil {
+		panic(err)
+	}
+	defer session.EndSession(context.TODO())
+
+	for i := 0; ctx.Err() == nil; i++ {
+		from := titles[rand.Intn(len(titles))]
+		to := titles[rand.Intn(len(titles))]
+
+		_, err := session.WithTransaction(ctx, func(ctx context.Context) (interface{}, error) {
+			_, err := courseColl.UpdateOne(ctx,
+				bson.D{{"title", from}},
+				bson.D{{"$inc", bson.D{{"enrollment", -1}}}})
+			if err != nil {
+				return nil, err
+			}
+			_, err = courseColl.UpdateOne(ctx,
+				bson.D{{"title", to}},
+				bson.D{{"$inc", bson.D{{"enrollment", 1}}}})
+			if err != nil {
+				return nil, err
+			}
+			return bookColl.InsertOne(ctx, Book{
+				Title:  fmt.Sprintf("Course Reader, Volume %d", i),
+				Author: "Faculty",
+				Length: 120,
+			})
+		})
+		if err != nil && ctx.Err() == nil {
+			panic(err)
+		}
+	}
+}
@@ -0,0 +1,160 @@
+// Exports query or aggregation results as canonical or relaxed Extended JSON
+// by using the Go driver
+//
+// Examples:
+//
+//	go run export.go -db db -coll courses -filter '{"enrollment": {"$gt": 20}}' \
+//		-projection '{"_id": 0}' -out courses.ndjson
+//	go run export.go -db db -coll tea -array -relaxed \
+//		-pipeline '[{"$group": {"_id": "$category", "count": {"$sum": 1}}}]'
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+
+	"github.com/mongodb/docs-golang/source/includes/fundamentals/code-snippets/tools/extjson"
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+)
+
+// start-config-struct
+type Config struct {
+	URI        string
+	Database   string
+	Collection string
+	Filter     string
+	Projection string
+	Sort       string
+	Limit      int64
+	Pipeline   string
+	Out        string
+	Array      bool
+	Relaxed    bool
+}
+
+// end-config-struct
+
+func main() {
+	var cfg Config
+
+	flag.StringVar(&cfg.URI, "uri", os.Getenv("MONGODB_URI"), "connection string (defaults to $MONGODB_URI)")
+	flag.StringVar(&cfg.Database, "db", "db", "database name")
+	flag.StringVar(&cfg.Collection, "coll", "", "collection name")
+	flag.StringVar(&cfg.Filter, "filter", "{}", "query filter as Extended JSON")
+	flag.StringVar(&cfg.Projection, "projection", "", "projection as Extended JSON")
+	flag.StringVar(&cfg.Sort, "sort", "", "sort specification as Extended JSON")
+	flag.Int64Var(&cfg.Limit, "limit", 0, "maximum number of documents to export")
+	flag.StringVar(&cfg.Pipeline, "pipeline", "", "aggregation pipeline as an Extended JSON array; replaces -filter, -projection, -sort and -limit")
+	flag.StringVar(&cfg.Out, "out", "", "output file (defaults to standard output)")
+	flag.BoolVar(&cfg.Array, "array", false, "write a JSON array instead of one document per line")
+	flag.BoolVar(&cfg.Relaxed, "relaxed", false, "write relaxed instead of canonical Extended JSON")
+	flag.Parse()
+
+	if cfg.URI == "" {
+		log.Fatal("You must set your 'MONGODB_URI' environment variable or pass -uri. See\n\t https://www.mongodb.com/docs/drivers/go/current/usage-examples/")
+	}
+	if cfg.Collection == "" {
+		log.Fatal("You must pass the collection to export with -coll")
+	}
+
+	client, err := mongo.Connect(options.Client().ApplyURI(cfg.URI))
+	if err != nil {
+		panic(err)
+	}
+	defer func() {
+		if err = client.Disconnect(context.TODO()); err != nil {
+			panic(err)
+		}
+	}()
+
+	var out io.Writer = os.Stdout
+	if cfg.Out != "" {
+		f, err := os.Create(cfg.Out)
+		if err != nil {
+			log.Fatal(err)
+		}
+		defer f.Close()
+		out = f
+	}
+
+	coll := client.Database(cfg.Database).Collection(cfg.Collection)
+	n, err := Export(context.TODO(), coll, cfg, out)
+	if err != nil {
+		log.Fatal(err)
+	}
+	fmt.Fprintf(os.Stderr, "Exported %d documents from %s.%s\n", n, cfg.Database, cfg.Collection)
+}
+
+// Runs the query or pipeline described by cfg and writes each result
+// document to w in the format that the import command reads. Returns the
+// number of documents written.
+// begin export
+func Export(ctx context.Context, coll *mongo.Collection, cfg Config, w io.Writer) (int64, error) {
+	cursor, err := openCursor(ctx, coll, cfg)
+	if err != nil {
+		return 0, err
+	}
+	defer cursor.Close(ctx)
+
+	// Writes each raw document from the cursor without decoding it into Go
+	// values, so no type information is lost
+	ew := extjson.NewWriter(w, cfg.Array, !cfg.Relaxed)
+	for cursor.Next(ctx) {
+		if err := ew.Write(cursor.Current); err != nil {
+			return ew.Count(), err
+		}
+	}
+	if err := cursor.Err(); err != nil {
+		return ew.Count(), err
+	}
+	return ew.Count(), ew.Close()
+}
+
+// end export
+
+// Opens an aggregation cursor if cfg has a pipeline, and a find cursor
+// otherwise
+// begin open cursor
+func openCursor(ctx context.Context, coll *mongo.Collection, cfg Config) (*mongo.Cursor, error) {
+	if cfg.Pipeline != "" {
+		var pipeline bson.A
+		if err := bson.UnmarshalExtJSON([]byte(cfg.Pipeline), false, &pipeline); err != nil {
+			return nil, fmt.Errorf("invalid -pipeline: %w", err)
+		}
+		return coll.Aggregate(ctx, pipeline)
+	}
+
+	var filter bson.D
+	if err := bson.UnmarshalExtJSON([]byte(cfg.Filter), false, &filter); err != nil {
+		return nil, fmt.Errorf("invalid -filter: %w", err)
+	}
+
+	opts := options.Find()
+	if cfg.Projection != "" {
+		var projection bson.D
+		if err := bson.UnmarshalExtJSON([]byte(cfg.Projection), false, &projection); err != nil {
+			return nil, fmt.Errorf("invalid -projection: %w", err)
+		}
+		opts.SetProjection(projection)
+	}
+	if cfg.Sort != "" {
+		var sort bson.D
+		if err := bson.UnmarshalExtJSON([]byte(cfg.Sort), false, &sort); err != nil {
+			return nil, fmt.Errorf("invalid -sort: %w", err)
+		}
+		opts.SetSort(sort)
+	}
+	if cfg.Limit > 0 {
+		opts.SetLimit(cfg.Limit)
+	}
+
+	return coll.Find(ctx, filter, opts)
+}
+
+// end open cursor
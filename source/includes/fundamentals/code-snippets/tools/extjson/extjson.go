@@ -0,0 +1,135 @@
+// Package extjson reads and writes files of Extended JSON documents, either
+// one document per line or as a JSON array. The export command writes files
+// with a Writer and the import command reads them with a Reader, so any file
+// that one command writes, the other can read.
+package extjson
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"io"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+// Writes documents as canonical or relaxed Extended JSON
+type Writer struct {
+	w         *bufio.Writer
+	array     bool
+	canonical bool
+	n         int64
+}
+
+// Returns a Writer that writes one document per line, or a JSON array if
+// array is true. Call Close to finish the output.
+func NewWriter(w io.Writer, array, canonical bool) *Writer {
+	return &Writer{w: bufio.NewWriter(w), array: array, canonical: canonical}
+}
+
+// Writes doc without decoding it into Go values, so no type information is
+// lost
+func (w *Writer) Write(doc bson.Raw) error {
+	b, err := bson.MarshalExtJSON(doc, w.canonical, false)
+	if err != nil {
+		return err
+	}
+
+	switch {
+	case w.array && w.n > 0:
+		w.w.WriteString(",\n")
+	case w.array:
+		w.w.WriteString("[\n")
+	}
+	w.w.Write(b)
+	if !w.array {
+		w.w.WriteString("\n")
+	}
+	w.n++
+	return nil
+}
+
+// Returns the number of documents written
+func (w *Writer) Count() int64 {
+	return w.n
+}
+
+// Finishes the JSON array, if any, and flushes the output
+func (w *Writer) Close() error {
+	if w.array {
+		if w.n == 0 {
+			w.w.WriteString("[")
+		}
+		w.w.WriteString("\n]\n")
+	}
+	return w.w.Flush()
+}
+
+// Reads Extended JSON documents from a stream of documents or from a JSON
+// array of documents. Accepts both canonical and relaxed Extended JSON.
+type Reader struct {
+	r     *bufio.Reader
+	dec   *json.Decoder
+	array bool
+}
+
+func NewReader(r io.Reader) *Reader {
+	return &Reader{r: bufio.NewReader(r)}
+}
+
+// Returns the next document, or io.EOF when the input is exhausted
+func (r *Reader) Next() (bson.D, error) {
+	if r.dec == nil {
+		if err := r.start(); err != nil {
+			return nil, err
+		}
+	}
+
+	if r.array && !r.dec.More() {
+		// Consumes the closing bracket and checks that nothing follows it
+		if _, err := r.dec.Token(); err != nil {
+			return nil, err
+		}
+		if _, err := r.dec.Token(); err != io.EOF {
+			return nil, errors.New("unexpected data after the JSON array")
+		}
+		return nil, io.EOF
+	}
+
+	var value json.RawMessage
+	if err := r.dec.Decode(&value); err != nil {
+		return nil, err
+	}
+	var doc bson.D
+	if err := bson.UnmarshalExtJSON(value, false, &doc); err != nil {
+		return nil, err
+	}
+	return doc, nil
+}
+
+// Checks whether the input is a JSON array by looking at its first
+// character that is not white space
+func (r *Reader) start() error {
+	for {
+		b, err := r.r.Peek(1)
+		if err == io.EOF {
+			r.dec = json.NewDecoder(r.r)
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		switch b[0] {
+		case ' ', '\t', '\r', '\n':
+			r.r.ReadByte()
+			continue
+		}
+
+		r.dec = json.NewDecoder(r.r)
+		if b[0] == '[' {
+			r.array = true
+			_, err = r.dec.Token()
+		}
+		return err
+	}
+}
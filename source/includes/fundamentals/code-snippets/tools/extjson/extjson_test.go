@@ -0,0 +1,193 @@
+package extjson
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+// Returns a document that contains every BSON type
+func everyType(t *testing.T) bson.Raw {
+	oid := bson.NewObjectID()
+	doc := bson.D{
+		{"double", 3.25},
+		{"wholeDouble", 42.0},
+		{"string", "Song of Solomon"},
+		{"document", bson.D{{"title", "Sula"}}},
+		{"array", bson.A{"a", int32(1), true}},
+		{"binary", bson.Binary{Subtype: 0x00, Data: []byte("binary data")}},
+		{"uuid", bson.Binary{Subtype: 0x04, Data: bytes.Repeat([]byte{0xAB}, 16)}},
+		{"undefined", bson.Undefined{}},
+		{"objectId", oid},
+		{"bool", true},
+		{"dateTime", bson.NewDateTimeFromTime(time.Date(2024, 5, 1, 12, 30, 0, 0, time.UTC))},
+		{"null", nil},
+		{"regex", bson.Regex{Pattern: "^Toni", Options: "i"}},
+		{"dbPointer", bson.DBPointer{DB: "db.books", Pointer: oid}},
+		{"javascript", bson.JavaScript("function() { return 1; }")},
+		{"symbol", bson.Symbol("symbol")},
+		{"codeWithScope", bson.CodeWithScope{Code: "function() { return x; }", Scope: bson.D{{"x", int32(1)}}}},
+		{"int32", int32(12)},
+		{"timestamp", bson.Timestamp{T: 1714566600, I: 1}},
+		{"int64", int64(1) << 40},
+		{"decimal128", bson.NewDecimal128(0x3040000000000000, 1234)},
+		{"minKey", bson.MinKey{}},
+		{"maxKey", bson.MaxKey{}},
+	}
+
+	b, err := bson.Marshal(doc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return b
+}
+
+// Writes documents that contain every BSON type and reads them back with the
+// Reader that the import command uses. Canonical Extended JSON must preserve
+// every value in both output formats.
+func TestCanonicalRoundTrip(t *testing.T) {
+	original := everyType(t)
+	elems, err := original.Elements()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, array := range []bool{false, true} {
+		t.Run(fmt.Sprintf("array=%v", array), func(t *testing.T) {
+			var buf bytes.Buffer
+			w := NewWriter(&buf, array, true)
+			for i := 0; i < 3; i++ {
+				if err := w.Write(original); err != nil {
+					t.Fatal(err)
+				}
+			}
+			if err := w.Close(); err != nil {
+				t.Fatal(err)
+			}
+
+			docs := readAll(t, NewReader(&buf))
+			if len(docs) != 3 {
+				t.Fatalf("read %d documents, want 3", len(docs))
+			}
+			for _, doc := range docs {
+				imported, err := bson.Marshal(doc)
+				if err != nil {
+					t.Fatal(err)
+				}
+				for _, elem := range elems {
+					got, err := bson.Raw(imported).LookupErr(elem.Key())
+					if err != nil || !got.Equal(elem.Value()) {
+						t.Errorf("%s: %s became %s", elem.Key(), elem.Value(), got)
+					}
+				}
+			}
+		})
+	}
+}
+
+// Relaxed Extended JSON writes numbers as plain JSON numbers, so an int64
+// that fits in 32 bits is read back as an int32. Checks that the Reader
+// accepts relaxed output and that only that value changes type.
+func TestRelaxedRoundTrip(t *testing.T) {
+	original, err := bson.Marshal(bson.D{
+		{"smallInt64", int64(5)},
+		{"int64", int64(1) << 40},
+		{"wholeDouble", 42.0},
+		{"dateTime", bson.NewDateTimeFromTime(time.Date(2024, 5, 1, 12, 30, 0, 0, time.UTC))},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var buf bytes.Buffer
+	w := NewWriter(&buf, false, false)
+	if err = w.Write(original); err != nil {
+		t.Fatal(err)
+	}
+	if err = w.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	docs := readAll(t, NewReader(&buf))
+	if len(docs) != 1 {
+		t.Fatalf("read %d documents, want 1", len(docs))
+	}
+	want := bson.D{
+		{"smallInt64", int32(5)},
+		{"int64", int64(1) << 40},
+		{"wholeDouble", 42.0},
+		{"dateTime", bson.NewDateTimeFromTime(time.Date(2024, 5, 1, 12, 30, 0, 0, time.UTC))},
+	}
+	if !reflect.DeepEqual(docs[0], want) {
+		t.Errorf("got %v, want %v", docs[0], want)
+	}
+}
+
+func TestReaderInputs(t *testing.T) {
+	cases := []struct {
+		name  string
+		input string
+		want  int
+		fails bool
+	}{
+		{"empty", "", 0, false},
+		{"white space only", " \n\t\n", 0, false},
+		{"one document per line", "{\"a\": 1}\n\n{\"a\": 2}\n", 2, false},
+		{"documents that span lines", "{\n  \"a\": 1\n}\n{\"a\": {\"$numberLong\": \"2\"}}", 2, false},
+		{"empty array", "[]", 0, false},
+		{"array with leading white space", "\n  [{\"a\": 1}, {\"a\": 2}]\n", 2, false},
+		{"data after the array", "[{\"a\": 1}] {\"a\": 2}", 1, true},
+		{"array of non-documents", "[1, 2]", 0, true},
+		{"invalid Extended JSON", "{\"a\": {\"$numberLong\": 2}}", 0, true},
+		{"truncated document", "{\"a\": 1", 0, true},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			r := NewReader(strings.NewReader(c.input))
+			n := 0
+			var err error
+			for {
+				_, err = r.Next()
+				if err != nil {
+					break
+				}
+				n++
+			}
+			if err == io.EOF {
+				err = nil
+			}
+
+			if c.fails && err == nil {
+				t.Errorf("read %d documents without an error, want an error", n)
+			}
+			if !c.fails && err != nil {
+				t.Errorf("got error %v", err)
+			}
+			if n != c.want {
+				t.Errorf("read %d documents, want %d", n, c.want)
+			}
+		})
+	}
+}
+
+func readAll(t *testing.T, r *Reader) []bson.D {
+	t.Helper()
+	var docs []bson.D
+	for {
+		doc, err := r.Next()
+		if err == io.EOF {
+			return docs
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		docs = append(docs, doc)
+	}
+}
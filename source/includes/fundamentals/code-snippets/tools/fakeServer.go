@@ -0,0 +1,39 @@
+// Runs the fake MongoDB server from the fakeserver package on a fixed
+// address, so that the examples can run with no deployment
+//
+// Examples:
+//
+//	go run fakeServer.go -addr localhost:27017
+//	MONGODB_URI="mongodb://localhost:27017/?directConnection=true" go run ../aggregation.go
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"os/signal"
+
+	"github.com/mongodb/docs-golang/source/includes/fundamentals/code-snippets/tools/fakeserver"
+)
+
+func main() {
+	addr := flag.String("addr", "localhost:27017", "address to listen on")
+	flag.Parse()
+
+	srv, err := fakeserver.Listen(*addr)
+	if err != nil {
+		log.Fatal(err)
+	}
+	fmt.Printf("Listening on %s. Connect with:\n\t%s\n", srv.Addr(), srv.URI())
+
+	// Stops the server when the user presses Ctrl+C
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	<-ctx.Done()
+
+	if err = srv.Close(); err != nil {
+		log.Fatal(err)
+	}
+}
@@ -0,0 +1,506 @@
+// Package fakeserver runs a fake MongoDB server in process, so that tests
+// can run the examples with no deployment. It stores documents in memory and
+// speaks enough of the wire protocol for the Go driver to connect and run
+// hello, ping, insert, find, getMore, aggregate, count, update, delete,
+// killCursors, drop, and endSessions commands.
+//
+// Queries support equality, comparison, $in, $nin, $exists, $and, $or, and
+// $nor conditions. Updates support replacement documents and the $set,
+// $unset, $inc, and $setOnInsert operators, with upserts. Aggregations
+// support the $match, $sort, $skip, $limit, $project, $unset, $count, and
+// $group stages. Other operators, stages, and commands return an error
+// reply, so a test that depends on one fails instead of silently seeing no
+// results. Tests can add commands with Handle.
+//
+// Example:
+//
+//	srv, err := fakeserver.Start()
+//	if err != nil {
+//		t.Fatal(err)
+//	}
+//	defer srv.Close()
+//
+//	client, err := mongo.Connect(options.Client().ApplyURI(srv.URI()))
+package fakeserver
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+// Answers one command. db is the database that the command runs on.
+type Handler func(db string, cmd bson.D) bson.D
+
+// Stores collections in memory and answers commands from any number of
+// connections
+type Server struct {
+	ln       net.Listener
+	wg       sync.WaitGroup
+	nextConn int32
+
+	mu          sync.Mutex
+	collections map[string][]bson.D // by namespace, in insertion order
+	cursors     map[int64]*cursorState
+	nextCursor  int64
+	handlers    map[string]Handler
+	conns       map[net.Conn]struct{}
+	closed      bool
+}
+
+type cursorState struct {
+	ns   string
+	docs []bson.D
+}
+
+// Starts a server on a free port of the loopback interface
+func Start() (*Server, error) {
+	return Listen("127.0.0.1:0")
+}
+
+// Starts a server that listens on addr
+func Listen(addr string) (*Server, error) {
+	ln, err := net.Listen("tcp", addr)
+	if err != nil {
+		return nil, err
+	}
+
+	s := &Server{
+		ln:          ln,
+		collections: map[string][]bson.D{},
+		cursors:     map[int64]*cursorState{},
+		handlers:    map[string]Handler{},
+		conns:       map[net.Conn]struct{}{},
+	}
+	s.wg.Add(1)
+	go s.serve()
+	return s, nil
+}
+
+// Returns the host and port that the server listens on
+func (s *Server) Addr() string {
+	return s.ln.Addr().String()
+}
+
+// Returns a connection string for the server
+func (s *Server) URI() string {
+	return fmt.Sprintf("mongodb://%s/?directConnection=true", s.Addr())
+}
+
+// Stops accepting connections, closes open connections, and waits for
+// their goroutines to finish
+func (s *Server) Close() error {
+	err := s.ln.Close()
+
+	s.mu.Lock()
+	s.closed = true
+	for conn := range s.conns {
+		conn.Close()
+	}
+	s.mu.Unlock()
+
+	s.wg.Wait()
+	return err
+}
+
+// Registers h to answer the named command, replacing the built-in handler
+// if there is one. Command names are not case sensitive. Handlers run
+// concurrently for different connections.
+func (s *Server) Handle(name string, h Handler) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.handlers[strings.ToLower(name)] = h
+}
+
+func (s *Server) serve() {
+	defer s.wg.Done()
+	for {
+		conn, err := s.ln.Accept()
+		if err != nil {
+			return
+		}
+
+		s.mu.Lock()
+		if s.closed {
+			s.mu.Unlock()
+			conn.Close()
+			return
+		}
+		s.conns[conn] = struct{}{}
+		s.wg.Add(1)
+		s.mu.Unlock()
+
+		go s.serveConn(conn, atomic.AddInt32(&s.nextConn, 1))
+	}
+}
+
+func (s *Server) serveConn(conn net.Conn, connID int32) {
+	defer s.wg.Done()
+	defer func() {
+		s.mu.Lock()
+		delete(s.conns, conn)
+		s.mu.Unlock()
+		conn.Close()
+	}()
+
+	for {
+		requestID, opCode, payload, err := readMessage(conn)
+		if err != nil {
+			return
+		}
+
+		switch opCode {
+		case opMsg:
+			cmd, err := parseOpMsg(payload)
+			if err != nil {
+				return
+			}
+			if err = writeOpMsg(conn, requestID, s.handle(cmd, connID)); err != nil {
+				return
+			}
+		case opQuery:
+			cmd, err := parseOpQuery(payload)
+			if err != nil {
+				return
+			}
+			if err = writeOpReply(conn, requestID, s.handle(cmd, connID)); err != nil {
+				return
+			}
+		default:
+			return
+		}
+	}
+}
+
+// Runs cmd and returns the reply document
+func (s *Server) handle(cmd bson.D, connID int32) bson.D {
+	if len(cmd) == 0 {
+		return ErrorReply(9, "FailedToParse", "empty command")
+	}
+	name := strings.ToLower(cmd[0].Key)
+	db, _ := lookupOr(cmd, "$db", "admin").(string)
+
+	s.mu.Lock()
+	h, ok := s.handlers[name]
+	s.mu.Unlock()
+	if ok {
+		return h(db, cmd)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var reply bson.D
+	var err error
+	switch name {
+	case "hello", "ismaster":
+		return helloReply(name, connID)
+	case "ping", "endsessions":
+		return bson.D{{"ok", 1.0}}
+	case "buildinfo":
+		return bson.D{{"version", "7.0.0"}, {"versionArray", bson.A{int32(7), int32(0), int32(0), int32(0)}}, {"ok", 1.0}}
+	case "insert":
+		reply, err = s.insert(db, cmd)
+	case "find":
+		reply, err = s.find(db, cmd)
+	case "getmore":
+		reply, err = s.getMore(db, cmd)
+	case "aggregate":
+		reply, err = s.aggregate(db, cmd)
+	case "count":
+		reply, err = s.count(db, cmd)
+	case "update":
+		reply, err = s.update(db, cmd)
+	case "delete":
+		reply, err = s.delete(db, cmd)
+	case "killcursors":
+		reply, err = s.killCursors(cmd)
+	case "drop":
+		reply, err = s.drop(db, cmd)
+	default:
+		err = &commandError{
+			code:     59,
+			codeName: "CommandNotFound",
+			msg:      fmt.Sprintf("no such command: '%s'", cmd[0].Key),
+		}
+	}
+
+	var ce *commandError
+	switch {
+	case errors.As(err, &ce):
+		return ErrorReply(ce.code, ce.codeName, ce.msg)
+	case err != nil:
+		return ErrorReply(8, "UnknownError", err.Error())
+	}
+	return reply
+}
+
+// Returns a reply to a command that failed
+func ErrorReply(code int32, codeName, msg string) bson.D {
+	return bson.D{{"ok", 0.0}, {"errmsg", msg}, {"code", code}, {"codeName", codeName}}
+}
+
+// Returns a reply that holds all of docs in the first batch of a cursor
+// that is already exhausted. Handlers can use it to answer commands that
+// return a cursor.
+func CursorReply(ns string, docs []bson.D) bson.D {
+	batch := bson.A{}
+	for _, doc := range docs {
+		batch = append(batch, doc)
+	}
+	return bson.D{
+		{"cursor", bson.D{{"firstBatch", batch}, {"id", int64(0)}, {"ns", ns}}},
+		{"ok", 1.0},
+	}
+}
+
+func helloReply(name string, connID int32) bson.D {
+	primaryField := "isWritablePrimary"
+	if name == "ismaster" {
+		primaryField = "ismaster"
+	}
+	return bson.D{
+		{"helloOk", true},
+		{primaryField, true},
+		{"maxBsonObjectSize", int32(16 * 1024 * 1024)},
+		{"maxMessageSizeBytes", int32(48000000)},
+		{"maxWriteBatchSize", int32(100000)},
+		{"localTime", bson.NewDateTimeFromTime(time.Now())},
+		{"logicalSessionTimeoutMinutes", int32(30)},
+		{"connectionId", connID},
+		{"minWireVersion", int32(0)},
+		{"maxWireVersion", int32(21)},
+		{"readOnly", false},
+		{"ok", 1.0},
+	}
+}
+
+// Returns the namespace of a command whose first field names a collection
+func namespace(db string, cmd bson.D) (string, error) {
+	coll, ok := cmd[0].Value.(string)
+	if !ok || coll == "" {
+		return "", badValue("collection name must be a nonempty string")
+	}
+	return db + "." + coll, nil
+}
+
+func (s *Server) insert(db string, cmd bson.D) (bson.D, error) {
+	ns, err := namespace(db, cmd)
+	if err != nil {
+		return nil, err
+	}
+	ordered := true
+	if v, ok := lookup(cmd, "ordered"); ok {
+		ordered, _ = v.(bool)
+	}
+
+	var n int32
+	writeErrors := bson.A{}
+	for i, v := range asArray(lookupOr(cmd, "documents", nil)) {
+		doc, ok := v.(bson.D)
+		if !ok {
+			return nil, badValue("documents must be objects")
+		}
+		id, ok := lookup(doc, "_id")
+		if !ok {
+			id = bson.NewObjectID()
+			doc = append(bson.D{{"_id", id}}, doc...)
+		}
+
+		if s.findByID(ns, id) {
+			writeErrors = append(writeErrors, bson.D{
+				{"index", int32(i)},
+				{"code", int32(11000)},
+				{"keyPattern", bson.D{{"_id", int32(1)}}},
+				{"keyValue", bson.D{{"_id", id}}},
+				{"errmsg", fmt.Sprintf("E11000 duplicate key error collection: %s index: _id_ dup key: { _id: %v }", ns, id)},
+			})
+			if ordered {
+				break
+			}
+			continue
+		}
+		s.collections[ns] = append(s.collections[ns], doc)
+		n++
+	}
+
+	reply := bson.D{{"n", n}}
+	if len(writeErrors) > 0 {
+		reply = append(reply, bson.E{"writeErrors", writeErrors})
+	}
+	return append(reply, bson.E{"ok", 1.0}), nil
+}
+
+func (s *Server) findByID(ns string, id interface{}) bool {
+	for _, doc := range s.collections[ns] {
+		if existing, _ := lookup(doc, "_id"); compare(existing, id) == 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// Returns the documents in ns that match filter, in insertion order
+func (s *Server) filter(ns string, filter bson.D) ([]bson.D, error) {
+	return filterDocs(s.collections[ns], filter)
+}
+
+func (s *Server) find(db string, cmd bson.D) (bson.D, error) {
+	ns, err := namespace(db, cmd)
+	if err != nil {
+		return nil, err
+	}
+	docs, err := s.filter(ns, asDocument(lookupOr(cmd, "filter", bson.D{})))
+	if err != nil {
+		return nil, err
+	}
+
+	if sortSpec, ok := lookup(cmd, "sort"); ok {
+		if err = sortDocs(docs, asDocument(sortSpec)); err != nil {
+			return nil, err
+		}
+	}
+	if skip, ok := asInt64(lookupOr(cmd, "skip", nil)); ok {
+		docs = docs[min(int(skip), len(docs)):]
+	}
+	limit, _ := asInt64(lookupOr(cmd, "limit", nil))
+	if limit < 0 {
+		limit = -limit
+	}
+	if limit > 0 && int(limit) < len(docs) {
+		docs = docs[:limit]
+	}
+	if proj, ok := lookup(cmd, "projection"); ok {
+		if docs, err = projectAll(docs, asDocument(proj)); err != nil {
+			return nil, err
+		}
+	}
+
+	batchSize, ok := asInt64(lookupOr(cmd, "batchSize", nil))
+	if !ok {
+		batchSize = 101
+	}
+	single, _ := lookupOr(cmd, "singleBatch", false).(bool)
+	return s.openCursor(ns, docs, batchSize, single), nil
+}
+
+func (s *Server) aggregate(db string, cmd bson.D) (bson.D, error) {
+	ns, err := namespace(db, cmd)
+	if err != nil {
+		return nil, err
+	}
+	docs := append([]bson.D{}, s.collections[ns]...)
+
+	for _, stage := range asArray(lookupOr(cmd, "pipeline", bson.A{})) {
+		if docs, err = runStage(docs, asDocument(stage)); err != nil {
+			return nil, err
+		}
+	}
+
+	cursorOpts := asDocument(lookupOr(cmd, "cursor", bson.D{}))
+	batchSize, ok := asInt64(lookupOr(cursorOpts, "batchSize", nil))
+	if !ok {
+		batchSize = 101
+	}
+	return s.openCursor(ns, docs, batchSize, false), nil
+}
+
+func (s *Server) count(db string, cmd bson.D) (bson.D, error) {
+	ns, err := namespace(db, cmd)
+	if err != nil {
+		return nil, err
+	}
+	docs, err := s.filter(ns, asDocument(lookupOr(cmd, "query", bson.D{})))
+	if err != nil {
+		return nil, err
+	}
+	return bson.D{{"n", int32(len(docs))}, {"ok", 1.0}}, nil
+}
+
+// Returns the first batchSize documents and stores the rest under a new
+// cursor ID, or returns cursor ID 0 if no documents remain
+func (s *Server) openCursor(ns string, docs []bson.D, batchSize int64, single bool) bson.D {
+	if batchSize <= 0 || int(batchSize) > len(docs) {
+		batchSize = int64(len(docs))
+	}
+	batch := bson.A{}
+	for _, doc := range docs[:batchSize] {
+		batch = append(batch, doc)
+	}
+
+	var id int64
+	if rest := docs[batchSize:]; len(rest) > 0 && !single {
+		s.nextCursor++
+		id = s.nextCursor
+		s.cursors[id] = &cursorState{ns: ns, docs: rest}
+	}
+
+	return bson.D{
+		{"cursor", bson.D{{"firstBatch", batch}, {"id", id}, {"ns", ns}}},
+		{"ok", 1.0},
+	}
+}
+
+// Returns the next batch of an open cursor. The cursor keeps its ID until
+// it is exhausted, and then the reply holds cursor ID 0.
+func (s *Server) getMore(db string, cmd bson.D) (bson.D, error) {
+	id, _ := asInt64(cmd[0].Value)
+	c, ok := s.cursors[id]
+	if !ok {
+		return nil, &commandError{code: 43, codeName: "CursorNotFound", msg: fmt.Sprintf("cursor id %d not found", id)}
+	}
+	if coll, _ := lookup(cmd, "collection"); c.ns != db+"."+fmt.Sprint(coll) {
+		return nil, &commandError{code: 13, codeName: "Unauthorized", msg: fmt.Sprintf("cursor %d belongs to %s", id, c.ns)}
+	}
+
+	n := len(c.docs)
+	if batchSize, ok := asInt64(lookupOr(cmd, "batchSize", nil)); ok && batchSize > 0 && int(batchSize) < n {
+		n = int(batchSize)
+	}
+	batch := bson.A{}
+	for _, doc := range c.docs[:n] {
+		batch = append(batch, doc)
+	}
+	c.docs = c.docs[n:]
+
+	if len(c.docs) == 0 {
+		delete(s.cursors, id)
+		id = 0
+	}
+	return bson.D{
+		{"cursor", bson.D{{"nextBatch", batch}, {"id", id}, {"ns", c.ns}}},
+		{"ok", 1.0},
+	}, nil
+}
+
+func (s *Server) killCursors(cmd bson.D) (bson.D, error) {
+	killed, notFound := bson.A{}, bson.A{}
+	for _, v := range asArray(lookupOr(cmd, "cursors", bson.A{})) {
+		id, _ := asInt64(v)
+		if _, ok := s.cursors[id]; ok {
+			delete(s.cursors, id)
+			killed = append(killed, id)
+		} else {
+			notFound = append(notFound, id)
+		}
+	}
+	return bson.D{{"cursorsKilled", killed}, {"cursorsNotFound", notFound}, {"ok", 1.0}}, nil
+}
+
+func (s *Server) drop(db string, cmd bson.D) (bson.D, error) {
+	ns, err := namespace(db, cmd)
+	if err != nil {
+		return nil, err
+	}
+	if _, ok := s.collections[ns]; !ok {
+		return nil, &commandError{code: 26, codeName: "NamespaceNotFound", msg: "ns not found"}
+	}
+	delete(s.collections, ns)
+	return bson.D{{"ns", ns}, {"ok", 1.0}}, nil
+}
@@ -0,0 +1,534 @@
+package fakeserver
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+)
+
+type Tea struct {
+	Type     string
+	Category string
+	Toppings []string
+	Price    float32
+}
+
+// The documents from aggregation.go
+var teas = []interface{}{
+	Tea{Type: "Masala", Category: "black", Toppings: []string{"ginger", "pumpkin spice", "cinnamon"}, Price: 6.75},
+	Tea{Type: "Gyokuro", Category: "green", Toppings: []string{"berries", "milk foam"}, Price: 5.65},
+	Tea{Type: "English Breakfast", Category: "black", Toppings: []string{"whipped cream", "honey"}, Price: 5.75},
+	Tea{Type: "Sencha", Category: "green", Toppings: []string{"lemon", "whipped cream"}, Price: 5.15},
+	Tea{Type: "Assam", Category: "black", Toppings: []string{"milk foam", "honey", "berries"}, Price: 5.65},
+	Tea{Type: "Matcha", Category: "green", Toppings: []string{"whipped cream", "honey"}, Price: 6.45},
+	Tea{Type: "Earl Grey", Category: "black", Toppings: []string{"milk foam", "pumpkin spice"}, Price: 6.15},
+	Tea{Type: "Hojicha", Category: "green", Toppings: []string{"lemon", "ginger", "milk foam"}, Price: 5.55},
+}
+
+// Starts a server and returns a client connected to it. Both are closed
+// when the test ends.
+func connect(t *testing.T) (*Server, *mongo.Client) {
+	t.Helper()
+	srv, err := Start()
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { srv.Close() })
+
+	client, err := mongo.Connect(options.Client().ApplyURI(srv.URI()).SetTimeout(5 * time.Second))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { client.Disconnect(context.Background()) })
+	return srv, client
+}
+
+// Returns the tea collection with the documents from aggregation.go
+func teaCollection(t *testing.T, client *mongo.Client) *mongo.Collection {
+	t.Helper()
+	coll := client.Database("db").Collection("tea")
+	result, err := coll.InsertMany(context.Background(), teas)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(result.InsertedIDs) != len(teas) {
+		t.Fatalf("inserted %d documents, want %d", len(result.InsertedIDs), len(teas))
+	}
+	return coll
+}
+
+func types(t *testing.T, cursor *mongo.Cursor) []string {
+	t.Helper()
+	var results []Tea
+	if err := cursor.All(context.Background(), &results); err != nil {
+		t.Fatal(err)
+	}
+	names := []string{}
+	for _, r := range results {
+		names = append(names, r.Type)
+	}
+	return names
+}
+
+func TestPing(t *testing.T) {
+	_, client := connect(t)
+	if err := client.Ping(context.Background(), nil); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestDuplicateKey(t *testing.T) {
+	_, client := connect(t)
+	coll := client.Database("db").Collection("duplicates")
+
+	_, err := coll.InsertMany(context.Background(), []interface{}{bson.D{{"_id", 1}}, bson.D{{"_id", 1}}})
+	if !mongo.IsDuplicateKeyError(err) {
+		t.Fatalf("got error %v, want a duplicate key error", err)
+	}
+	if n, err := coll.CountDocuments(context.Background(), bson.D{}); err != nil || n != 1 {
+		t.Errorf("counted %d documents with error %v, want 1", n, err)
+	}
+}
+
+func TestFindWithFilterSortAndProjection(t *testing.T) {
+	_, client := connect(t)
+	coll := teaCollection(t, client)
+
+	opts := options.Find().SetSort(bson.D{{"price", -1}}).SetProjection(bson.D{{"_id", 0}, {"type", 1}})
+	filter := bson.D{{"category", "green"}, {"price", bson.D{{"$gte", 5.5}}}}
+	cursor, err := coll.Find(context.Background(), filter, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []string{"Matcha", "Gyokuro", "Hojicha"}
+	if got := types(t, cursor); !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
+
+func TestFindOneWithNoMatch(t *testing.T) {
+	_, client := connect(t)
+	coll := teaCollection(t, client)
+
+	err := coll.FindOne(context.Background(), bson.D{{"type", "Chai"}}).Err()
+	if !errors.Is(err, mongo.ErrNoDocuments) {
+		t.Errorf("got error %v, want mongo.ErrNoDocuments", err)
+	}
+}
+
+// Iterates a cursor across several getMore batches, and checks with raw
+// commands that the cursor keeps its ID until it is exhausted
+func TestGetMore(t *testing.T) {
+	_, client := connect(t)
+	coll := teaCollection(t, client)
+	ctx := context.Background()
+
+	cursor, err := coll.Find(ctx, bson.D{}, options.Find().SetBatchSize(3))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := types(t, cursor); len(got) != len(teas) {
+		t.Errorf("iterated %d documents, want %d", len(got), len(teas))
+	}
+
+	type batchReply struct {
+		Cursor struct {
+			ID         int64    `bson:"id"`
+			FirstBatch []bson.D `bson:"firstBatch"`
+			NextBatch  []bson.D `bson:"nextBatch"`
+		} `bson:"cursor"`
+	}
+	db := client.Database("db")
+
+	var first batchReply
+	err = db.RunCommand(ctx, bson.D{{"find", "tea"}, {"batchSize", 3}}).Decode(&first)
+	if err != nil {
+		t.Fatal(err)
+	}
+	id := first.Cursor.ID
+	if id == 0 || len(first.Cursor.FirstBatch) != 3 {
+		t.Fatalf("first batch has %d documents and cursor ID %d", len(first.Cursor.FirstBatch), id)
+	}
+
+	for i, want := range []struct {
+		n  int
+		id int64
+	}{{3, id}, {2, 0}} {
+		var next batchReply
+		err = db.RunCommand(ctx, bson.D{{"getMore", id}, {"collection", "tea"}, {"batchSize", 3}}).Decode(&next)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(next.Cursor.NextBatch) != want.n || next.Cursor.ID != want.id {
+			t.Errorf("getMore %d: got %d documents and cursor ID %d, want %d and %d",
+				i+1, len(next.Cursor.NextBatch), next.Cursor.ID, want.n, want.id)
+		}
+	}
+
+	err = db.RunCommand(ctx, bson.D{{"getMore", id}, {"collection", "tea"}}).Err()
+	var ce mongo.CommandError
+	if !errors.As(err, &ce) || ce.Code != 43 {
+		t.Errorf("getMore on an exhausted cursor: got error %v, want CursorNotFound", err)
+	}
+}
+
+func TestAggregate(t *testing.T) {
+	_, client := connect(t)
+	coll := teaCollection(t, client)
+	ctx := context.Background()
+
+	t.Run("group", func(t *testing.T) {
+		// The pipeline from the average example in aggregation.go
+		groupStage := bson.D{
+			{"$group", bson.D{
+				{"_id", "$category"},
+				{"average_price", bson.D{{"$avg", "$price"}}},
+				{"type_total", bson.D{{"$sum", 1}}},
+			}}}
+		cursor, err := coll.Aggregate(ctx, mongo.Pipeline{groupStage})
+		if err != nil {
+			t.Fatal(err)
+		}
+		var results []struct {
+			Category string  `bson:"_id"`
+			Average  float64 `bson:"average_price"`
+			Total    int32   `bson:"type_total"`
+		}
+		if err = cursor.All(ctx, &results); err != nil {
+			t.Fatal(err)
+		}
+		if len(results) != 2 || results[0].Total != 4 || results[1].Total != 4 {
+			t.Fatalf("got %+v, want two categories of four teas", results)
+		}
+		if a := results[0].Average; a < 6.07 || a > 6.08 {
+			t.Errorf("got black tea average %v, want about 6.075", a)
+		}
+	})
+
+	t.Run("unset", func(t *testing.T) {
+		// The pipeline from the unset example in aggregation.go. The order
+		// matches the output on the aggregation page of the docs.
+		matchStage := bson.D{{"$match", bson.D{{"toppings", "milk foam"}}}}
+		unsetStage := bson.D{{"$unset", bson.A{"_id", "category"}}}
+		sortStage := bson.D{{"$sort", bson.D{{"price", 1}, {"toppings", 1}}}}
+		limitStage := bson.D{{"$limit", 2}}
+		cursor, err := coll.Aggregate(ctx, mongo.Pipeline{matchStage, unsetStage, sortStage, limitStage})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		var results []bson.M
+		if err = cursor.All(ctx, &results); err != nil {
+			t.Fatal(err)
+		}
+		if len(results) != 2 || results[0]["type"] != "Hojicha" || results[1]["type"] != "Gyokuro" {
+			t.Errorf("got %v, want Hojicha and Gyokuro", results)
+		}
+		for _, r := range results {
+			if _, ok := r["category"]; ok {
+				t.Errorf("$unset did not remove the category field from %v", r)
+			}
+		}
+	})
+}
+
+// Checks that operators, stages, and expressions that the server does not
+// implement fail instead of matching nothing
+// Returns the tea with the given type, or fails the test
+func findTea(t *testing.T, coll *mongo.Collection, teaType string) bson.M {
+	t.Helper()
+	var doc bson.M
+	if err := coll.FindOne(context.Background(), bson.D{{"type", teaType}}).Decode(&doc); err != nil {
+		t.Fatalf("find %s: %v", teaType, err)
+	}
+	return doc
+}
+
+func TestUpdate(t *testing.T) {
+	_, client := connect(t)
+	coll := teaCollection(t, client)
+	ctx := context.Background()
+
+	result, err := coll.UpdateOne(ctx, bson.D{{"type", "Masala"}}, bson.D{
+		{"$set", bson.D{{"price", 7.25}, {"origin.country", "India"}}},
+		{"$inc", bson.D{{"orders", int32(2)}}},
+		{"$unset", bson.D{{"toppings", ""}}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.MatchedCount != 1 || result.ModifiedCount != 1 {
+		t.Errorf("got %d matched and %d modified, want 1 and 1", result.MatchedCount, result.ModifiedCount)
+	}
+	masala := findTea(t, coll, "Masala")
+	if masala["price"] != 7.25 || masala["orders"] != int32(2) || masala["toppings"] != nil {
+		t.Errorf("got %v after the update", masala)
+	}
+	if origin := masala["origin"]; !reflect.DeepEqual(origin, bson.D{{"country", "India"}}) {
+		t.Errorf("got origin %v, want the nested country", masala["origin"])
+	}
+
+	// Increments an existing field, and matches without modifying when a
+	// $set does not change the document
+	if _, err = coll.UpdateOne(ctx, bson.D{{"type", "Masala"}}, bson.D{{"$inc", bson.D{{"orders", int32(-1)}}}}); err != nil {
+		t.Fatal(err)
+	}
+	if got := findTea(t, coll, "Masala")["orders"]; got != int32(1) {
+		t.Errorf("got orders %v after $inc, want 1", got)
+	}
+	result, err = coll.UpdateOne(ctx, bson.D{{"type", "Masala"}}, bson.D{{"$set", bson.D{{"price", 7.25}}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.MatchedCount != 1 || result.ModifiedCount != 0 {
+		t.Errorf("got %d matched and %d modified, want 1 and 0", result.MatchedCount, result.ModifiedCount)
+	}
+
+	result, err = coll.UpdateMany(ctx, bson.D{{"category", "green"}}, bson.D{{"$set", bson.D{{"caffeine", "low"}}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.MatchedCount != 4 || result.ModifiedCount != 4 {
+		t.Errorf("UpdateMany matched %d and modified %d, want 4 and 4", result.MatchedCount, result.ModifiedCount)
+	}
+
+	replaced, err := coll.ReplaceOne(ctx, bson.D{{"type", "Assam"}}, bson.D{{"type", "Assam"}, {"price", 5.0}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if replaced.ModifiedCount != 1 {
+		t.Errorf("ReplaceOne modified %d documents, want 1", replaced.ModifiedCount)
+	}
+	if assam := findTea(t, coll, "Assam"); len(assam) != 3 || assam["_id"] == nil {
+		t.Errorf("got %v after the replacement, want _id, type, and price", assam)
+	}
+}
+
+func TestUpsert(t *testing.T) {
+	_, client := connect(t)
+	coll := teaCollection(t, client)
+	ctx := context.Background()
+
+	update := bson.D{
+		{"$set", bson.D{{"price", 6.0}}},
+		{"$setOnInsert", bson.D{{"category", "oolong"}}},
+	}
+	result, err := coll.UpdateOne(ctx, bson.D{{"type", "Tieguanyin"}}, update, options.UpdateOne().SetUpsert(true))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.UpsertedCount != 1 || result.UpsertedID == nil {
+		t.Fatalf("got %+v, want one upserted document", result)
+	}
+	doc := findTea(t, coll, "Tieguanyin")
+	if doc["_id"] != result.UpsertedID || doc["price"] != 6.0 || doc["category"] != "oolong" {
+		t.Errorf("got upserted document %v", doc)
+	}
+
+	// $setOnInsert does not apply when the document exists
+	update = bson.D{
+		{"$set", bson.D{{"price", 6.5}}},
+		{"$setOnInsert", bson.D{{"category", "changed"}}},
+	}
+	result, err = coll.UpdateOne(ctx, bson.D{{"type", "Tieguanyin"}}, update, options.UpdateOne().SetUpsert(true))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.UpsertedCount != 0 || result.MatchedCount != 1 {
+		t.Errorf("got %+v, want one matched document", result)
+	}
+	if doc = findTea(t, coll, "Tieguanyin"); doc["price"] != 6.5 || doc["category"] != "oolong" {
+		t.Errorf("got %v after the second upsert", doc)
+	}
+}
+
+func TestDelete(t *testing.T) {
+	_, client := connect(t)
+	coll := teaCollection(t, client)
+	ctx := context.Background()
+
+	result, err := coll.DeleteOne(ctx, bson.D{{"category", "black"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.DeletedCount != 1 {
+		t.Errorf("DeleteOne deleted %d documents, want 1", result.DeletedCount)
+	}
+	result, err = coll.DeleteMany(ctx, bson.D{{"price", bson.D{{"$gt", 6}}}}, options.DeleteMany().SetHint(bson.D{{"_id", 1}}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.DeletedCount != 2 {
+		t.Errorf("DeleteMany deleted %d documents, want Matcha and Earl Grey", result.DeletedCount)
+	}
+
+	cursor, err := coll.Find(ctx, bson.D{}, options.Find().SetSort(bson.D{{"type", 1}}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"Assam", "English Breakfast", "Gyokuro", "Hojicha", "Sencha"}
+	if got := types(t, cursor); !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v after the deletes, want %v", got, want)
+	}
+}
+
+func TestUnsupportedFeatures(t *testing.T) {
+	_, client := connect(t)
+	ctx := context.Background()
+
+	// Runs each case against an empty collection and against a collection
+	// with documents, because an empty collection must fail too
+	empty := client.Database("db").Collection("empty")
+	full := teaCollection(t, client)
+
+	finds := []struct {
+		name   string
+		filter bson.D
+		code   int32
+	}{
+		{"$regex", bson.D{{"type", bson.D{{"$regex", "^M"}}}}, 2},
+		{"$elemMatch", bson.D{{"toppings", bson.D{{"$elemMatch", bson.D{{"$eq", "honey"}}}}}}, 2},
+		{"$not", bson.D{{"price", bson.D{{"$not", bson.D{{"$gt", 6}}}}}}, 2},
+		{"$where", bson.D{{"$where", "this.price > 6"}}, 2},
+		{"$expr", bson.D{{"$expr", bson.D{{"$gt", bson.A{"$price", 6}}}}}, 2},
+		{"nested in $or", bson.D{{"$or", bson.A{bson.D{{"type", "Masala"}}, bson.D{{"type", bson.D{{"$size", 1}}}}}}}, 2},
+		{"$in without an array", bson.D{{"type", bson.D{{"$in", "Masala"}}}}, 2},
+	}
+	pipelines := []struct {
+		name     string
+		pipeline mongo.Pipeline
+		code     int32
+	}{
+		{"$lookup stage", mongo.Pipeline{{{"$lookup", bson.D{{"from", "other"}}}}}, 40324},
+		{"$match with $regex", mongo.Pipeline{{{"$match", bson.D{{"type", bson.D{{"$regex", "^M"}}}}}}}, 2},
+		{"$addToSet accumulator", mongo.Pipeline{{{"$group", bson.D{{"_id", nil}, {"s", bson.D{{"$addToSet", "$type"}}}}}}}, 15952},
+		{"$multiply expression", mongo.Pipeline{{{"$project", bson.D{{"cents", bson.D{{"$multiply", bson.A{"$price", 100}}}}}}}}, 168},
+		{"$sort by text score", mongo.Pipeline{{{"$sort", bson.D{{"score", bson.D{{"$meta", "textScore"}}}}}}}, 2},
+	}
+	updates := []struct {
+		name   string
+		update interface{}
+		code   int
+	}{
+		{"$push modifier", bson.D{{"$push", bson.D{{"toppings", "honey"}}}}, 9},
+		{"$inc with a string", bson.D{{"$inc", bson.D{{"price", "1"}}}}, 14},
+		{"pipeline update", mongo.Pipeline{{{"$set", bson.D{{"price", 1}}}}}, 2},
+	}
+
+	for _, coll := range []*mongo.Collection{empty, full} {
+		for _, c := range finds {
+			t.Run(coll.Name()+"/find/"+c.name, func(t *testing.T) {
+				_, err := coll.Find(ctx, c.filter)
+				checkCode(t, err, c.code)
+			})
+		}
+		for _, c := range updates {
+			t.Run(coll.Name()+"/update/"+c.name, func(t *testing.T) {
+				_, err := coll.UpdateOne(ctx, bson.D{{"type", "Masala"}}, c.update)
+				var we mongo.WriteException
+				if !errors.As(err, &we) || len(we.WriteErrors) != 1 || we.WriteErrors[0].Code != c.code {
+					t.Errorf("got error %v, want a write error with code %d", err, c.code)
+				}
+			})
+		}
+		for _, c := range pipelines {
+			if coll == empty && c.code == 168 {
+				// Expressions are evaluated only for documents
+				continue
+			}
+			t.Run(coll.Name()+"/aggregate/"+c.name, func(t *testing.T) {
+				_, err := coll.Aggregate(ctx, c.pipeline)
+				checkCode(t, err, c.code)
+			})
+		}
+	}
+}
+
+func checkCode(t *testing.T, err error, code int32) {
+	t.Helper()
+	var ce mongo.CommandError
+	if !errors.As(err, &ce) || ce.Code != code {
+		t.Errorf("got error %v, want a command error with code %d", err, code)
+	}
+}
+
+func TestUnknownCommand(t *testing.T) {
+	_, client := connect(t)
+	err := client.Database("db").RunCommand(context.Background(), bson.D{{"notACommand", 1}}).Err()
+	checkCode(t, err, 59)
+}
+
+func TestDrop(t *testing.T) {
+	_, client := connect(t)
+	coll := teaCollection(t, client)
+	ctx := context.Background()
+
+	if err := coll.Drop(ctx); err != nil {
+		t.Fatal(err)
+	}
+	n, err := coll.CountDocuments(ctx, bson.D{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != 0 {
+		t.Errorf("counted %d documents after drop, want 0", n)
+	}
+
+	// Dropping a collection that does not exist succeeds, because the
+	// driver ignores the NamespaceNotFound error
+	if err = coll.Drop(ctx); err != nil {
+		t.Errorf("second drop: %v", err)
+	}
+}
+
+func TestHandle(t *testing.T) {
+	srv, client := connect(t)
+	srv.Handle("listWidgets", func(db string, cmd bson.D) bson.D {
+		return CursorReply(db+".widgets", []bson.D{{{"name", "sprocket"}}})
+	})
+
+	cursor, err := client.Database("shop").RunCommandCursor(context.Background(), bson.D{{"listWidgets", 1}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var results []bson.D
+	if err = cursor.All(context.Background(), &results); err != nil {
+		t.Fatal(err)
+	}
+	if want := []bson.D{{{"name", "sprocket"}}}; !reflect.DeepEqual(results, want) {
+		t.Errorf("got %v, want %v", results, want)
+	}
+}
+
+// Checks that Close returns while clients still hold open connections and
+// that the client then fails to reach the server
+func TestClose(t *testing.T) {
+	srv, client := connect(t)
+	if err := client.Ping(context.Background(), nil); err != nil {
+		t.Fatal(err)
+	}
+
+	done := make(chan error, 1)
+	go func() { done <- srv.Close() }()
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatal(err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Close did not return")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	if err := client.Ping(ctx, nil); err == nil {
+		t.Error("Ping succeeded after Close")
+	}
+}
@@ -0,0 +1,707 @@
+package fakeserver
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+// An error that the server returns to the client in a command reply
+type commandError struct {
+	code     int32
+	codeName string
+	msg      string
+}
+
+func (e *commandError) Error() string {
+	return e.msg
+}
+
+func badValue(format string, args ...interface{}) error {
+	return &commandError{code: 2, codeName: "BadValue", msg: fmt.Sprintf(format, args...)}
+}
+
+// Reports whether doc satisfies every condition in filter. Returns a
+// BadValue error for operators that the server does not implement, so that
+// a query that uses one fails instead of silently matching nothing.
+func matches(doc bson.D, filter bson.D) (bool, error) {
+	result := true
+	for _, e := range filter {
+		if strings.HasPrefix(e.Key, "$") {
+			ok, err := matchLogical(doc, e)
+			if err != nil {
+				return false, err
+			}
+			result = result && ok
+			continue
+		}
+
+		value, exists := lookupPath(doc, e.Key)
+		if cond, ok := e.Value.(bson.D); ok && len(cond) > 0 && strings.HasPrefix(cond[0].Key, "$") {
+			ok, err := matchOperators(value, exists, cond)
+			if err != nil {
+				return false, err
+			}
+			result = result && ok
+		} else {
+			result = result && matchValue(value, func(v interface{}) bool { return compare(v, e.Value) == 0 })
+		}
+	}
+	return result, nil
+}
+
+// Returns the documents that match filter. Checks filter against an empty
+// document first, because matches evaluates every condition, so that an
+// unsupported operator fails even when there are no documents.
+func filterDocs(docs []bson.D, filter bson.D) ([]bson.D, error) {
+	if _, err := matches(bson.D{}, filter); err != nil {
+		return nil, err
+	}
+	var out []bson.D
+	for _, doc := range docs {
+		ok, err := matches(doc, filter)
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			out = append(out, doc)
+		}
+	}
+	return out, nil
+}
+
+// Evaluates a top-level $and, $or, or $nor condition
+func matchLogical(doc bson.D, e bson.E) (bool, error) {
+	switch e.Key {
+	case "$and", "$or", "$nor":
+	default:
+		return false, badValue("unknown or unsupported top level operator: %s", e.Key)
+	}
+
+	clauses, ok := e.Value.(bson.A)
+	if !ok || len(clauses) == 0 {
+		return false, badValue("%s must be a nonempty array", e.Key)
+	}
+	any, all := false, true
+	for _, c := range clauses {
+		clause, ok := c.(bson.D)
+		if !ok {
+			return false, badValue("%s argument's entries must be objects", e.Key)
+		}
+		ok, err := matches(doc, clause)
+		if err != nil {
+			return false, err
+		}
+		any = any || ok
+		all = all && ok
+	}
+
+	switch e.Key {
+	case "$and":
+		return all, nil
+	case "$or":
+		return any, nil
+	default:
+		return !any, nil
+	}
+}
+
+func matchOperators(value interface{}, exists bool, cond bson.D) (bool, error) {
+	result := true
+	for _, op := range cond {
+		var ok bool
+		switch op.Key {
+		case "$eq":
+			ok = matchValue(value, func(v interface{}) bool { return compare(v, op.Value) == 0 })
+		case "$ne":
+			ok = !matchValue(value, func(v interface{}) bool { return compare(v, op.Value) == 0 })
+		case "$gt":
+			ok = exists && matchValue(value, func(v interface{}) bool { return sameType(v, op.Value) && compare(v, op.Value) > 0 })
+		case "$gte":
+			ok = exists && matchValue(value, func(v interface{}) bool { return sameType(v, op.Value) && compare(v, op.Value) >= 0 })
+		case "$lt":
+			ok = exists && matchValue(value, func(v interface{}) bool { return sameType(v, op.Value) && compare(v, op.Value) < 0 })
+		case "$lte":
+			ok = exists && matchValue(value, func(v interface{}) bool { return sameType(v, op.Value) && compare(v, op.Value) <= 0 })
+		case "$in", "$nin":
+			values, isArray := op.Value.(bson.A)
+			if !isArray {
+				return false, badValue("%s needs an array", op.Key)
+			}
+			ok = matchValue(value, func(v interface{}) bool { return contains(values, v) })
+			if op.Key == "$nin" {
+				ok = !ok
+			}
+		case "$exists":
+			ok = exists == truthy(op.Value)
+		default:
+			return false, badValue("unknown or unsupported operator: %s", op.Key)
+		}
+		result = result && ok
+	}
+	return result, nil
+}
+
+// Reports whether pred holds for value or, if value is an array, for the
+// array or any of its elements
+func matchValue(value interface{}, pred func(interface{}) bool) bool {
+	if pred(value) {
+		return true
+	}
+	if a, ok := value.(bson.A); ok {
+		for _, v := range a {
+			if pred(v) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func contains(values bson.A, v interface{}) bool {
+	for _, candidate := range values {
+		if compare(v, candidate) == 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// Returns the rank of a value's type in the BSON comparison order
+func typeRank(v interface{}) int {
+	switch v.(type) {
+	case nil:
+		return 1
+	case int32, int64, float64:
+		return 2
+	case string:
+		return 3
+	case bson.D:
+		return 4
+	case bson.A:
+		return 5
+	case bson.Binary:
+		return 6
+	case bson.ObjectID:
+		return 7
+	case bool:
+		return 8
+	case bson.DateTime:
+		return 9
+	default:
+		return 10
+	}
+}
+
+func sameType(a, b interface{}) bool {
+	return typeRank(a) == typeRank(b)
+}
+
+// Compares two values in the BSON comparison order
+func compare(a, b interface{}) int {
+	if ra, rb := typeRank(a), typeRank(b); ra != rb {
+		return ra - rb
+	}
+
+	switch a := a.(type) {
+	case nil:
+		return 0
+	case int32, int64, float64:
+		fa, _ := asFloat(a)
+		fb, _ := asFloat(b)
+		switch {
+		case fa < fb:
+			return -1
+		case fa > fb:
+			return 1
+		}
+		return 0
+	case string:
+		return strings.Compare(a, b.(string))
+	case bson.ObjectID:
+		return strings.Compare(a.Hex(), b.(bson.ObjectID).Hex())
+	case bool:
+		switch bb := b.(bool); {
+		case a == bb:
+			return 0
+		case !a:
+			return -1
+		}
+		return 1
+	case bson.DateTime:
+		switch bb := b.(bson.DateTime); {
+		case a < bb:
+			return -1
+		case a > bb:
+			return 1
+		}
+		return 0
+	case bson.A:
+		bb := b.(bson.A)
+		for i := 0; i < len(a) && i < len(bb); i++ {
+			if c := compare(a[i], bb[i]); c != 0 {
+				return c
+			}
+		}
+		return len(a) - len(bb)
+	case bson.D:
+		bb := b.(bson.D)
+		for i := 0; i < len(a) && i < len(bb); i++ {
+			if c := strings.Compare(a[i].Key, bb[i].Key); c != 0 {
+				return c
+			}
+			if c := compare(a[i].Value, bb[i].Value); c != 0 {
+				return c
+			}
+		}
+		return len(a) - len(bb)
+	default:
+		return strings.Compare(fmt.Sprint(a), fmt.Sprint(b))
+	}
+}
+
+// Sorts docs in place. As on a real server, an array sorts by its smallest
+// element in an ascending sort and by its largest in a descending sort.
+func sortDocs(docs []bson.D, spec bson.D) error {
+	if len(spec) == 0 {
+		return badValue("$sort specification must not be empty")
+	}
+	dirs := make([]int64, len(spec))
+	for i, key := range spec {
+		dir, ok := asInt64(key.Value)
+		if !ok || dir != 1 && dir != -1 {
+			return badValue("unsupported sort order for %s: %v", key.Key, key.Value)
+		}
+		dirs[i] = dir
+	}
+
+	sort.SliceStable(docs, func(i, j int) bool {
+		for k, key := range spec {
+			a, _ := lookupPath(docs[i], key.Key)
+			b, _ := lookupPath(docs[j], key.Key)
+			c := compare(sortKey(a, dirs[k] < 0), sortKey(b, dirs[k] < 0))
+			if dirs[k] < 0 {
+				c = -c
+			}
+			if c != 0 {
+				return c < 0
+			}
+		}
+		return false
+	})
+	return nil
+}
+
+func sortKey(v interface{}, descending bool) interface{} {
+	a, ok := v.(bson.A)
+	if !ok || len(a) == 0 {
+		return v
+	}
+	key := a[0]
+	for _, elem := range a[1:] {
+		if c := compare(elem, key); descending && c > 0 || !descending && c < 0 {
+			key = elem
+		}
+	}
+	return key
+}
+
+func projectAll(docs []bson.D, spec bson.D) ([]bson.D, error) {
+	out := make([]bson.D, len(docs))
+	for i, doc := range docs {
+		var err error
+		if out[i], err = project(doc, spec); err != nil {
+			return nil, err
+		}
+	}
+	return out, nil
+}
+
+// Applies an inclusion or exclusion projection. In an inclusion projection,
+// a value that is a "$field" path or an expression document sets a
+// computed field.
+func project(doc bson.D, spec bson.D) (bson.D, error) {
+	inclusion := false
+	includeID := true
+	for _, e := range spec {
+		if e.Key == "_id" {
+			if _, isNum := asFloat(e.Value); isNum || isBool(e.Value) {
+				includeID = truthy(e.Value)
+				continue
+			}
+		}
+		if _, isNum := asFloat(e.Value); !(isNum || isBool(e.Value)) || truthy(e.Value) {
+			inclusion = true
+		}
+	}
+
+	if !inclusion {
+		out := bson.D{}
+		for _, e := range doc {
+			v, listed := lookup(spec, e.Key)
+			if e.Key == "_id" && !includeID || listed && !truthy(v) {
+				continue
+			}
+			out = append(out, e)
+		}
+		return out, nil
+	}
+
+	out := bson.D{}
+	if id, ok := lookup(doc, "_id"); ok && includeID {
+		out = append(out, bson.E{"_id", id})
+	}
+	for _, e := range spec {
+		if e.Key == "_id" {
+			continue
+		}
+		if _, isNum := asFloat(e.Value); isNum || isBool(e.Value) {
+			if !truthy(e.Value) {
+				return nil, badValue("cannot exclude %s in an inclusion projection", e.Key)
+			}
+			if v, ok := lookupPath(doc, e.Key); ok {
+				out = append(out, bson.E{e.Key, v})
+			}
+			continue
+		}
+		v, err := evaluate(e.Value, doc)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, bson.E{e.Key, v})
+	}
+	return out, nil
+}
+
+// Evaluates an aggregation expression. Supports "$field" paths, documents
+// of expressions, and literal values. Returns an error for expression
+// operators such as $multiply, which the server does not implement.
+func evaluate(expr interface{}, doc bson.D) (interface{}, error) {
+	switch e := expr.(type) {
+	case string:
+		if strings.HasPrefix(e, "$") {
+			v, _ := lookupPath(doc, e[1:])
+			return v, nil
+		}
+		return e, nil
+	case bson.D:
+		out := bson.D{}
+		for _, f := range e {
+			if strings.HasPrefix(f.Key, "$") {
+				return nil, &commandError{
+					code:     168,
+					codeName: "InvalidPipelineOperator",
+					msg:      fmt.Sprintf("unrecognized or unsupported expression '%s'", f.Key),
+				}
+			}
+			v, err := evaluate(f.Value, doc)
+			if err != nil {
+				return nil, err
+			}
+			out = append(out, bson.E{f.Key, v})
+		}
+		return out, nil
+	default:
+		return expr, nil
+	}
+}
+
+func runStage(docs []bson.D, stage bson.D) ([]bson.D, error) {
+	if len(stage) != 1 {
+		return nil, &commandError{
+			code:     40323,
+			codeName: "Location40323",
+			msg:      "a pipeline stage specification object must contain exactly one field",
+		}
+	}
+	name, arg := stage[0].Key, stage[0].Value
+
+	switch name {
+	case "$match":
+		return filterDocs(docs, asDocument(arg))
+	case "$sort":
+		return docs, sortDocs(docs, asDocument(arg))
+	case "$skip", "$limit":
+		n, ok := asInt64(arg)
+		if !ok || n < 0 || name == "$limit" && n == 0 {
+			return nil, badValue("invalid argument to %s: %v", name, arg)
+		}
+		if name == "$skip" {
+			return docs[min(int(n), len(docs)):], nil
+		}
+		return docs[:min(int(n), len(docs))], nil
+	case "$project":
+		return projectAll(docs, asDocument(arg))
+	case "$unset":
+		spec := bson.D{}
+		fields := asArray(arg)
+		if field, ok := arg.(string); ok {
+			fields = bson.A{field}
+		}
+		for _, f := range fields {
+			spec = append(spec, bson.E{fmt.Sprint(f), int32(0)})
+		}
+		return projectAll(docs, spec)
+	case "$count":
+		return []bson.D{{{fmt.Sprint(arg), int32(len(docs))}}}, nil
+	case "$group":
+		return group(docs, asDocument(arg))
+	default:
+		return nil, &commandError{
+			code:     40324,
+			codeName: "Location40324",
+			msg:      fmt.Sprintf("unrecognized or unsupported pipeline stage name: '%s'", name),
+		}
+	}
+}
+
+func unknownAccumulator(op string) error {
+	return &commandError{
+		code:     15952,
+		codeName: "Location15952",
+		msg:      fmt.Sprintf("unknown or unsupported group operator '%s'", op),
+	}
+}
+
+type accumulator struct {
+	op     string
+	values []interface{}
+}
+
+// Groups documents by the _id expression of spec and computes each
+// accumulator field
+func group(docs []bson.D, spec bson.D) ([]bson.D, error) {
+	idExpr, ok := lookup(spec, "_id")
+	if !ok {
+		return nil, badValue("a group specification must include an _id")
+	}
+	// Checks the accumulators first, so that an unsupported one fails even
+	// when there are no documents
+	for _, e := range spec {
+		acc := asDocument(e.Value)
+		if e.Key == "_id" {
+			continue
+		}
+		if len(acc) != 1 {
+			return nil, badValue("the %s field must specify one accumulator", e.Key)
+		}
+		switch acc[0].Key {
+		case "$sum", "$avg", "$min", "$max", "$first", "$push":
+		default:
+			return nil, unknownAccumulator(acc[0].Key)
+		}
+	}
+
+	type groupState struct {
+		id     interface{}
+		fields []accumulator
+	}
+	var groups []*groupState
+
+	for _, doc := range docs {
+		id, err := evaluate(idExpr, doc)
+		if err != nil {
+			return nil, err
+		}
+		var g *groupState
+		for _, candidate := range groups {
+			if compare(candidate.id, id) == 0 {
+				g = candidate
+				break
+			}
+		}
+		if g == nil {
+			g = &groupState{id: id}
+			groups = append(groups, g)
+		}
+
+		i := 0
+		for _, e := range spec {
+			if e.Key == "_id" {
+				continue
+			}
+			acc := asDocument(e.Value)
+			if len(g.fields) <= i {
+				g.fields = append(g.fields, accumulator{op: acc[0].Key})
+			}
+			v, err := evaluate(acc[0].Value, doc)
+			if err != nil {
+				return nil, err
+			}
+			g.fields[i].values = append(g.fields[i].values, v)
+			i++
+		}
+	}
+
+	out := make([]bson.D, 0, len(groups))
+	for _, g := range groups {
+		doc := bson.D{{"_id", g.id}}
+		i := 0
+		for _, e := range spec {
+			if e.Key == "_id" {
+				continue
+			}
+			value, err := accumulate(g.fields[i])
+			if err != nil {
+				return nil, err
+			}
+			doc = append(doc, bson.E{e.Key, value})
+			i++
+		}
+		out = append(out, doc)
+	}
+	return out, nil
+}
+
+func accumulate(acc accumulator) (interface{}, error) {
+	switch acc.op {
+	case "$sum", "$avg":
+		var sum float64
+		var intSum int64
+		allInt32, allInt := true, true
+		count := 0
+		for _, v := range acc.values {
+			f, ok := asFloat(v)
+			if !ok {
+				continue
+			}
+			sum += f
+			count++
+			switch n := v.(type) {
+			case int32:
+				intSum += int64(n)
+			case int64:
+				intSum += n
+				allInt32 = false
+			default:
+				allInt32, allInt = false, false
+			}
+		}
+		if acc.op == "$avg" {
+			if count == 0 {
+				return nil, nil
+			}
+			return sum / float64(count), nil
+		}
+		switch {
+		case allInt32 && intSum >= -1<<31 && intSum < 1<<31:
+			return int32(intSum), nil
+		case allInt:
+			return intSum, nil
+		}
+		return sum, nil
+	case "$min", "$max":
+		var best interface{}
+		for _, v := range acc.values {
+			if v == nil {
+				continue
+			}
+			if best == nil || acc.op == "$min" && compare(v, best) < 0 || acc.op == "$max" && compare(v, best) > 0 {
+				best = v
+			}
+		}
+		return best, nil
+	case "$first":
+		if len(acc.values) == 0 {
+			return nil, nil
+		}
+		return acc.values[0], nil
+	case "$push":
+		return bson.A(acc.values), nil
+	default:
+		return nil, unknownAccumulator(acc.op)
+	}
+}
+
+// Returns the value of a top-level field
+func lookup(d bson.D, key string) (interface{}, bool) {
+	for _, e := range d {
+		if e.Key == key {
+			return e.Value, true
+		}
+	}
+	return nil, false
+}
+
+func lookupOr(d bson.D, key string, fallback interface{}) interface{} {
+	if v, ok := lookup(d, key); ok {
+		return v
+	}
+	return fallback
+}
+
+// Returns the value at a dotted path. A path through an array collects the
+// values from each element that has the remaining path.
+func lookupPath(d bson.D, path string) (interface{}, bool) {
+	first, rest, nested := strings.Cut(path, ".")
+	v, ok := lookup(d, first)
+	if !ok || !nested {
+		return v, ok
+	}
+
+	switch v := v.(type) {
+	case bson.D:
+		return lookupPath(v, rest)
+	case bson.A:
+		values := bson.A{}
+		for _, elem := range v {
+			if doc, ok := elem.(bson.D); ok {
+				if found, ok := lookupPath(doc, rest); ok {
+					values = append(values, found)
+				}
+			}
+		}
+		return values, len(values) > 0
+	default:
+		return nil, false
+	}
+}
+
+func asDocument(v interface{}) bson.D {
+	d, _ := v.(bson.D)
+	return d
+}
+
+func asArray(v interface{}) bson.A {
+	a, _ := v.(bson.A)
+	return a
+}
+
+func asInt64(v interface{}) (int64, bool) {
+	switch n := v.(type) {
+	case int32:
+		return int64(n), true
+	case int64:
+		return n, true
+	case float64:
+		return int64(n), true
+	}
+	return 0, false
+}
+
+func asFloat(v interface{}) (float64, bool) {
+	switch n := v.(type) {
+	case int32:
+		return float64(n), true
+	case int64:
+		return float64(n), true
+	case float64:
+		return n, true
+	}
+	return 0, false
+}
+
+func isBool(v interface{}) bool {
+	_, ok := v.(bool)
+	return ok
+}
+
+func truthy(v interface{}) bool {
+	if b, ok := v.(bool); ok {
+		return b
+	}
+	f, ok := asFloat(v)
+	return ok && f != 0
+}
@@ -0,0 +1,322 @@
+package fakeserver
+
+import (
+	"fmt"
+	"strings"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+func (s *Server) update(db string, cmd bson.D) (bson.D, error) {
+	ns, err := namespace(db, cmd)
+	if err != nil {
+		return nil, err
+	}
+	ordered := true
+	if v, ok := lookup(cmd, "ordered"); ok {
+		ordered, _ = v.(bool)
+	}
+
+	var n, modified int32
+	upserted, writeErrors := bson.A{}, bson.A{}
+	for i, v := range asArray(lookupOr(cmd, "updates", nil)) {
+		stmt, ok := v.(bson.D)
+		if !ok {
+			return nil, badValue("updates must be objects")
+		}
+		matched, changed, id, err := s.updateOne(ns, stmt)
+		if err != nil {
+			ce, ok := err.(*commandError)
+			if !ok {
+				return nil, err
+			}
+			writeErrors = append(writeErrors, bson.D{
+				{"index", int32(i)},
+				{"code", ce.code},
+				{"errmsg", ce.msg},
+			})
+			if ordered {
+				break
+			}
+			continue
+		}
+		n += matched
+		modified += changed
+		if id != nil {
+			n++
+			upserted = append(upserted, bson.D{{"index", int32(i)}, {"_id", id}})
+		}
+	}
+
+	reply := bson.D{{"n", n}, {"nModified", modified}}
+	if len(upserted) > 0 {
+		reply = append(reply, bson.E{"upserted", upserted})
+	}
+	if len(writeErrors) > 0 {
+		reply = append(reply, bson.E{"writeErrors", writeErrors})
+	}
+	return append(reply, bson.E{"ok", 1.0}), nil
+}
+
+// Runs one update statement. Returns the number of documents that matched
+// and that changed, and the _id of an upserted document or nil.
+func (s *Server) updateOne(ns string, stmt bson.D) (matched, changed int32, upsertedID interface{}, err error) {
+	filter := asDocument(lookupOr(stmt, "q", bson.D{}))
+	u, ok := lookupOr(stmt, "u", nil).(bson.D)
+	if !ok {
+		return 0, 0, nil, badValue("the update must be a document; pipeline updates are not supported")
+	}
+	multi, _ := lookupOr(stmt, "multi", false).(bool)
+	upsert, _ := lookupOr(stmt, "upsert", false).(bool)
+
+	// Checks the update against an empty document first, so that an
+	// unsupported modifier fails even when no document matches
+	if _, err = applyUpdate(bson.D{}, u, true); err != nil {
+		return 0, 0, nil, err
+	}
+	if _, err = matches(bson.D{}, filter); err != nil {
+		return 0, 0, nil, err
+	}
+
+	docs := s.collections[ns]
+	for i, doc := range docs {
+		ok, err := matches(doc, filter)
+		if err != nil {
+			return 0, 0, nil, err
+		}
+		if !ok {
+			continue
+		}
+
+		updated, err := applyUpdate(doc, u, false)
+		if err != nil {
+			return 0, 0, nil, err
+		}
+		matched++
+		if compare(updated, doc) != 0 {
+			changed++
+			docs[i] = updated
+		}
+		if !multi {
+			break
+		}
+	}
+	if matched > 0 || !upsert {
+		return matched, changed, nil, nil
+	}
+
+	// Builds the new document from the equality conditions of the filter
+	doc := bson.D{}
+	for _, e := range filter {
+		if strings.HasPrefix(e.Key, "$") {
+			continue
+		}
+		if cond, ok := e.Value.(bson.D); ok && len(cond) > 0 && strings.HasPrefix(cond[0].Key, "$") {
+			continue
+		}
+		doc = setPath(doc, e.Key, e.Value)
+	}
+	if doc, err = applyUpdate(doc, u, true); err != nil {
+		return 0, 0, nil, err
+	}
+	id, ok := lookup(doc, "_id")
+	if !ok {
+		id = bson.NewObjectID()
+		doc = append(bson.D{{"_id", id}}, doc...)
+	}
+	if s.findByID(ns, id) {
+		return 0, 0, nil, &commandError{code: 11000, codeName: "DuplicateKey", msg: fmt.Sprintf("E11000 duplicate key error collection: %s index: _id_ dup key: { _id: %v }", ns, id)}
+	}
+	s.collections[ns] = append(s.collections[ns], doc)
+	return 0, 0, id, nil
+}
+
+// Returns a copy of doc with update applied. An update whose first key does
+// not start with "$" replaces every field but _id. insert reports whether the
+// update creates a new document, in which case $setOnInsert applies.
+func applyUpdate(doc bson.D, update bson.D, insert bool) (bson.D, error) {
+	if len(update) == 0 || !strings.HasPrefix(update[0].Key, "$") {
+		out := bson.D{}
+		if id, ok := lookup(doc, "_id"); ok {
+			out = append(out, bson.E{"_id", id})
+		}
+		for _, e := range update {
+			if strings.HasPrefix(e.Key, "$") {
+				return nil, badValue("the replacement document must not contain update operators")
+			}
+			if e.Key == "_id" {
+				if len(out) > 0 && compare(out[0].Value, e.Value) != 0 {
+					return nil, &commandError{code: 66, codeName: "ImmutableField", msg: "the (immutable) field '_id' was found to have been altered"}
+				}
+				if len(out) > 0 {
+					continue
+				}
+			}
+			out = append(out, e)
+		}
+		return out, nil
+	}
+
+	out := copyDoc(doc)
+	for _, op := range update {
+		fields, ok := op.Value.(bson.D)
+		if !ok {
+			return nil, &commandError{code: 9, codeName: "FailedToParse", msg: fmt.Sprintf("modifier %s must be an object", op.Key)}
+		}
+		for _, f := range fields {
+			if f.Key == "_id" && op.Key != "$setOnInsert" && !insert {
+				if v, ok := lookup(out, "_id"); !ok || compare(v, f.Value) != 0 {
+					return nil, &commandError{code: 66, codeName: "ImmutableField", msg: "performing an update on the path '_id' would modify the immutable field '_id'"}
+				}
+			}
+			switch op.Key {
+			case "$set":
+				out = setPath(out, f.Key, f.Value)
+			case "$setOnInsert":
+				if insert {
+					out = setPath(out, f.Key, f.Value)
+				}
+			case "$unset":
+				out = unsetPath(out, f.Key)
+			case "$inc":
+				delta, ok := asFloat(f.Value)
+				if !ok {
+					return nil, &commandError{code: 14, codeName: "TypeMismatch", msg: fmt.Sprintf("cannot increment with non-numeric argument: {%s: %v}", f.Key, f.Value)}
+				}
+				current, exists := lookupPath(out, f.Key)
+				if !exists {
+					out = setPath(out, f.Key, f.Value)
+					continue
+				}
+				sum, err := add(current, f.Value, delta)
+				if err != nil {
+					return nil, err
+				}
+				out = setPath(out, f.Key, sum)
+			default:
+				return nil, &commandError{code: 9, codeName: "FailedToParse", msg: fmt.Sprintf("unknown or unsupported modifier: %s", op.Key)}
+			}
+		}
+	}
+	return out, nil
+}
+
+// Adds two numbers, keeping the wider of their types as the server does
+func add(current, increment interface{}, delta float64) (interface{}, error) {
+	switch c := current.(type) {
+	case int32:
+		switch i := increment.(type) {
+		case int32:
+			return c + i, nil
+		case int64:
+			return int64(c) + i, nil
+		}
+		return float64(c) + delta, nil
+	case int64:
+		if i, ok := asInt64(increment); ok && !isFloat(increment) {
+			return c + i, nil
+		}
+		return float64(c) + delta, nil
+	case float64:
+		return c + delta, nil
+	}
+	return nil, &commandError{code: 14, codeName: "TypeMismatch", msg: fmt.Sprintf("cannot apply $inc to a value of non-numeric type: %v", current)}
+}
+
+func isFloat(v interface{}) bool {
+	_, ok := v.(float64)
+	return ok
+}
+
+// Returns a copy of d in which nested documents are also copied, so that
+// changes to the copy do not affect stored documents
+func copyDoc(d bson.D) bson.D {
+	out := make(bson.D, len(d))
+	for i, e := range d {
+		if nested, ok := e.Value.(bson.D); ok {
+			e.Value = copyDoc(nested)
+		}
+		out[i] = e
+	}
+	return out
+}
+
+// Sets the value at a dotted path, creating documents along the path
+func setPath(d bson.D, path string, value interface{}) bson.D {
+	first, rest, nested := strings.Cut(path, ".")
+	for i := range d {
+		if d[i].Key != first {
+			continue
+		}
+		if !nested {
+			d[i].Value = value
+		} else {
+			sub, _ := d[i].Value.(bson.D)
+			d[i].Value = setPath(sub, rest, value)
+		}
+		return d
+	}
+	if !nested {
+		return append(d, bson.E{first, value})
+	}
+	return append(d, bson.E{first, setPath(bson.D{}, rest, value)})
+}
+
+// Removes the value at a dotted path
+func unsetPath(d bson.D, path string) bson.D {
+	first, rest, nested := strings.Cut(path, ".")
+	for i := range d {
+		if d[i].Key != first {
+			continue
+		}
+		if !nested {
+			return append(d[:i:i], d[i+1:]...)
+		}
+		if sub, ok := d[i].Value.(bson.D); ok {
+			d[i].Value = unsetPath(sub, rest)
+		}
+		return d
+	}
+	return d
+}
+
+func (s *Server) delete(db string, cmd bson.D) (bson.D, error) {
+	ns, err := namespace(db, cmd)
+	if err != nil {
+		return nil, err
+	}
+
+	var n int32
+	for _, v := range asArray(lookupOr(cmd, "deletes", nil)) {
+		stmt, ok := v.(bson.D)
+		if !ok {
+			return nil, badValue("deletes must be objects")
+		}
+		filter := asDocument(lookupOr(stmt, "q", bson.D{}))
+		limit, _ := asInt64(lookupOr(stmt, "limit", int32(0)))
+		if _, err := matches(bson.D{}, filter); err != nil {
+			return nil, err
+		}
+
+		// A limit of 0 deletes every match, and a limit of 1 deletes the
+		// first
+		var deleted int64
+		kept := []bson.D{}
+		for _, doc := range s.collections[ns] {
+			ok, err := matches(doc, filter)
+			if err != nil {
+				return nil, err
+			}
+			if ok && (limit == 0 || deleted < limit) {
+				deleted++
+				continue
+			}
+			kept = append(kept, doc)
+		}
+		n += int32(deleted)
+		if _, exists := s.collections[ns]; exists {
+			s.collections[ns] = kept
+		}
+	}
+	return bson.D{{"n", n}, {"ok", 1.0}}, nil
+}
@@ -0,0 +1,183 @@
+package fakeserver
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"sync/atomic"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+// Operation codes from the MongoDB wire protocol
+const (
+	opReply = 1
+	opQuery = 2004
+	opMsg   = 2013
+)
+
+// Reads one message and returns its request ID, operation code, and the
+// bytes that follow the 16-byte header
+func readMessage(r io.Reader) (requestID, opCode int32, payload []byte, err error) {
+	var header [16]byte
+	if _, err = io.ReadFull(r, header[:]); err != nil {
+		return 0, 0, nil, err
+	}
+	length := int32(binary.LittleEndian.Uint32(header[0:]))
+	requestID = int32(binary.LittleEndian.Uint32(header[4:]))
+	opCode = int32(binary.LittleEndian.Uint32(header[12:]))
+	if length < 16 || length > 48000000 {
+		return 0, 0, nil, fmt.Errorf("invalid message length %d", length)
+	}
+
+	payload = make([]byte, length-16)
+	_, err = io.ReadFull(r, payload)
+	return requestID, opCode, payload, err
+}
+
+// Returns the command in an OP_MSG payload. Document sequences, such as the
+// "documents" of an insert, become array fields of the command.
+func parseOpMsg(payload []byte) (bson.D, error) {
+	if len(payload) < 5 {
+		return nil, errors.New("OP_MSG is too short")
+	}
+	flags := binary.LittleEndian.Uint32(payload)
+	payload = payload[4:]
+	// Ignores the checksum that follows the sections when bit 0 is set
+	if flags&1 != 0 {
+		payload = payload[:len(payload)-4]
+	}
+
+	var body bson.D
+	var sequences bson.D
+	for len(payload) > 0 {
+		kind := payload[0]
+		payload = payload[1:]
+
+		switch kind {
+		case 0:
+			doc, rest, err := readDocument(payload)
+			if err != nil {
+				return nil, err
+			}
+			if err = bson.Unmarshal(doc, &body); err != nil {
+				return nil, err
+			}
+			payload = rest
+		case 1:
+			if len(payload) < 4 {
+				return nil, errors.New("document sequence is too short")
+			}
+			size := int(binary.LittleEndian.Uint32(payload))
+			if size < 4 || size > len(payload) {
+				return nil, fmt.Errorf("invalid document sequence size %d", size)
+			}
+			section := payload[4:size]
+			payload = payload[size:]
+
+			end := bytes.IndexByte(section, 0)
+			if end < 0 {
+				return nil, errors.New("document sequence has no identifier")
+			}
+			identifier := string(section[:end])
+			section = section[end+1:]
+
+			docs := bson.A{}
+			for len(section) > 0 {
+				doc, rest, err := readDocument(section)
+				if err != nil {
+					return nil, err
+				}
+				var d bson.D
+				if err = bson.Unmarshal(doc, &d); err != nil {
+					return nil, err
+				}
+				docs = append(docs, d)
+				section = rest
+			}
+			sequences = append(sequences, bson.E{identifier, docs})
+		default:
+			return nil, fmt.Errorf("unknown OP_MSG section kind %d", kind)
+		}
+	}
+	if body == nil {
+		return nil, errors.New("OP_MSG has no body")
+	}
+	return append(body, sequences...), nil
+}
+
+// Returns the command in an OP_QUERY payload, which the driver sends only
+// for the first hello on each connection
+func parseOpQuery(payload []byte) (bson.D, error) {
+	if len(payload) < 4 {
+		return nil, errors.New("OP_QUERY is too short")
+	}
+	payload = payload[4:] // flags
+	end := bytes.IndexByte(payload, 0)
+	if end < 0 || len(payload) < end+9 {
+		return nil, errors.New("OP_QUERY has no collection name")
+	}
+	payload = payload[end+9:] // collection name, numberToSkip, numberToReturn
+
+	doc, _, err := readDocument(payload)
+	if err != nil {
+		return nil, err
+	}
+	var query bson.D
+	if err = bson.Unmarshal(doc, &query); err != nil {
+		return nil, err
+	}
+	if inner, ok := lookup(query, "$query"); ok {
+		if d, ok := inner.(bson.D); ok {
+			query = d
+		}
+	}
+	return query, nil
+}
+
+// Splits the first BSON document from b
+func readDocument(b []byte) (doc, rest []byte, err error) {
+	if len(b) < 5 {
+		return nil, nil, errors.New("document is too short")
+	}
+	size := int(binary.LittleEndian.Uint32(b))
+	if size < 5 || size > len(b) {
+		return nil, nil, fmt.Errorf("invalid document size %d", size)
+	}
+	return b[:size], b[size:], nil
+}
+
+var nextRequestID int32
+
+// Writes doc as an OP_MSG reply to the request with the given ID
+func writeOpMsg(w io.Writer, responseTo int32, doc bson.D) error {
+	data, err := bson.Marshal(doc)
+	if err != nil {
+		return err
+	}
+	payload := append([]byte{0, 0, 0, 0, 0}, data...) // flags, section kind 0
+	return writeMessage(w, responseTo, opMsg, payload)
+}
+
+// Writes doc as an OP_REPLY to a legacy OP_QUERY request
+func writeOpReply(w io.Writer, responseTo int32, doc bson.D) error {
+	data, err := bson.Marshal(doc)
+	if err != nil {
+		return err
+	}
+	payload := make([]byte, 20, 20+len(data))
+	binary.LittleEndian.PutUint32(payload[16:], 1) // numberReturned
+	return writeMessage(w, responseTo, opReply, append(payload, data...))
+}
+
+func writeMessage(w io.Writer, responseTo, opCode int32, payload []byte) error {
+	header := make([]byte, 16, 16+len(payload))
+	binary.LittleEndian.PutUint32(header[0:], uint32(16+len(payload)))
+	binary.LittleEndian.PutUint32(header[4:], uint32(atomic.AddInt32(&nextRequestID, 1)))
+	binary.LittleEndian.PutUint32(header[8:], uint32(responseTo))
+	binary.LittleEndian.PutUint32(header[12:], uint32(opCode))
+	_, err := w.Write(append(header, payload...))
+	return err
+}
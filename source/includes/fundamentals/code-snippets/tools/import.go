@@ -0,0 +1,567 @@
+// Imports CSV or Extended JSON files into a collection with concurrent bulk
+// writes by using the Go driver. Reads Extended JSON files that the export
+// command writes, either one document per line or as a JSON array.
+//
+// Example:
+//
+//	go run import.go -db db -coll courses -file courses.csv \
+//		-types "enrollment:int,startDate:date" -upsert-key title \
+//		-progress courses.progress
+package main
+
+import (
+	"context"
+	"encoding/csv"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/mongodb/docs-golang/source/includes/fundamentals/code-snippets/tools/extjson"
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+)
+
+// start-config-struct
+type Config struct {
+	URI          string
+	Database     string
+	Collection   string
+	File         string
+	Format       string
+	Workers      int
+	BatchSize    int
+	UpsertKeys   []string
+	Types        map[string]string
+	IgnoreBlanks bool
+	ProgressFile string
+}
+
+// end-config-struct
+
+// Holds a group of documents that are written in one bulk write. Start is
+// the position of the first document in the input file.
+type batch struct {
+	seq   int
+	start int64
+	docs  []bson.D
+}
+
+func main() {
+	cfg := parseFlags()
+
+	client, err := mongo.Connect(options.Client().ApplyURI(cfg.URI))
+	if err != nil {
+		panic(err)
+	}
+	defer func() {
+		if err = client.Disconnect(context.TODO()); err != nil {
+			panic(err)
+		}
+	}()
+
+	// Stops reading input when the user presses Ctrl+C. Batches already
+	// sent finish, and the progress file records where to resume.
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	coll := client.Database(cfg.Database).Collection(cfg.Collection)
+	imported, err := Import(ctx, coll, cfg)
+	fmt.Printf("Imported %d documents into %s.%s\n", imported, cfg.Database, cfg.Collection)
+	if err != nil {
+		log.Fatal(err)
+	}
+}
+
+func parseFlags() Config {
+	var cfg Config
+	var upsertKeys, types string
+
+	flag.StringVar(&cfg.URI, "uri", os.Getenv("MONGODB_URI"), "connection string (defaults to $MONGODB_URI)")
+	flag.StringVar(&cfg.Database, "db", "db", "database name")
+	flag.StringVar(&cfg.Collection, "coll", "", "collection name (defaults to the file name)")
+	flag.StringVar(&cfg.File, "file", "", "CSV, NDJSON, or JSON array file to import")
+	flag.StringVar(&cfg.Format, "format", "", `"csv" or "ndjson", which also reads a JSON array of documents (defaults to the file extension)`)
+	flag.IntVar(&cfg.Workers, "workers", 4, "number of concurrent bulk writes")
+	flag.IntVar(&cfg.BatchSize, "batch", 1000, "number of documents in each bulk write")
+	flag.StringVar(&upsertKeys, "upsert-key", "", "comma-separated fields that identify a document; replaces matching documents instead of inserting")
+	flag.StringVar(&types, "types", "", `comma-separated "field:type" coercion rules for CSV input`)
+	flag.BoolVar(&cfg.IgnoreBlanks, "ignore-blanks", false, "omit empty CSV fields instead of storing empty strings")
+	flag.StringVar(&cfg.ProgressFile, "progress", "", "file that records progress so that an interrupted import can resume")
+	flag.Parse()
+
+	if cfg.URI == "" {
+		log.Fatal("You must set your 'MONGODB_URI' environment variable or pass -uri. See\n\t https://www.mongodb.com/docs/drivers/go/current/usage-examples/")
+	}
+	if cfg.File == "" {
+		log.Fatal("You must pass the file to import with -file")
+	}
+	if cfg.Collection == "" {
+		cfg.Collection = strings.TrimSuffix(filepath.Base(cfg.File), filepath.Ext(cfg.File))
+	}
+	if cfg.Format == "" {
+		cfg.Format = strings.TrimPrefix(strings.ToLower(filepath.Ext(cfg.File)), ".")
+	}
+	if cfg.Format == "json" || cfg.Format == "jsonl" {
+		cfg.Format = "ndjson"
+	}
+	if cfg.Format != "csv" && cfg.Format != "ndjson" {
+		log.Fatalf("Unsupported format %q: use -format csv or -format ndjson", cfg.Format)
+	}
+	if cfg.Workers < 1 || cfg.BatchSize < 1 {
+		log.Fatal("-workers and -batch must be at least 1")
+	}
+	if upsertKeys != "" {
+		cfg.UpsertKeys = strings.Split(upsertKeys, ",")
+	}
+
+	cfg.Types = map[string]string{}
+	if types != "" {
+		for _, rule := range strings.Split(types, ",") {
+			field, typ, ok := strings.Cut(rule, ":")
+			if !ok {
+				log.Fatalf("Invalid type rule %q: use field:type", rule)
+			}
+			if _, err := coerce(typ, ""); errors.Is(err, errUnknownType) {
+				log.Fatalf("Invalid type rule %q: %v", rule, err)
+			}
+			cfg.Types[field] = typ
+		}
+	}
+
+	return cfg
+}
+
+// Reads cfg.File and writes its documents to coll. Returns the number of
+// documents that were written.
+// begin import
+func Import(ctx context.Context, coll *mongo.Collection, cfg Config) (int64, error) {
+	f, err := os.Open(cfg.File)
+	if err != nil {
+		return 0, err
+	}
+	defer f.Close()
+
+	// Skips the documents that a previous run already wrote
+	progress, err := loadProgress(cfg.ProgressFile)
+	if err != nil {
+		return 0, err
+	}
+	// Workers advance progress.committed as batches finish, so the reader
+	// uses the count from the start of the run
+	skip := progress.committed
+	if skip > 0 {
+		fmt.Printf("Resuming after %d documents\n", skip)
+	}
+
+	var reader documentReader
+	if cfg.Format == "csv" {
+		reader, err = newCSVReader(f, cfg.Types, cfg.IgnoreBlanks)
+	} else {
+		reader = extjson.NewReader(f)
+	}
+	if err != nil {
+		return 0, err
+	}
+
+	// Stops reading input as soon as any batch fails
+	readCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	batches := make(chan batch, cfg.Workers)
+	errs := make(chan error, cfg.Workers+1)
+	var written int64
+	var mu sync.Mutex
+
+	// Writes each batch with a separate unordered bulk write. Batches use a
+	// background context so that a batch in progress finishes when the
+	// import is interrupted.
+	var wg sync.WaitGroup
+	for i := 0; i < cfg.Workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for b := range batches {
+				// Records that the batch might be written before writing it, so
+				// that a resumed import knows which documents could already
+				// be in the collection
+				end := b.start + int64(len(b.docs))
+				if err := progress.started(end); err != nil {
+					errs <- err
+					cancel()
+					return
+				}
+
+				// Counts the documents at the start of the batch that a
+				// previous run might have written
+				resent := int(min(max(progress.resumeThrough-b.start, 0), int64(len(b.docs))))
+				n, err := writeBatch(context.Background(), coll, b.docs, cfg.UpsertKeys, resent)
+				if err != nil {
+					errs <- fmt.Errorf("documents %d to %d: %w", b.start, b.start+int64(len(b.docs))-1, err)
+					cancel()
+					return
+				}
+				mu.Lock()
+				written += n
+				mu.Unlock()
+				if err = progress.complete(b.seq, end); err != nil {
+					errs <- err
+					cancel()
+					return
+				}
+			}
+		}()
+	}
+
+	// Reads documents and groups them into batches
+	go func() {
+		defer close(batches)
+
+		var pos int64
+		var seq int
+		docs := make([]bson.D, 0, cfg.BatchSize)
+		send := func() bool {
+			b := batch{seq: seq, start: pos - int64(len(docs)), docs: docs}
+			seq++
+			docs = make([]bson.D, 0, cfg.BatchSize)
+			select {
+			case batches <- b:
+				return true
+			case <-readCtx.Done():
+				return false
+			}
+		}
+
+		for {
+			doc, err := reader.Next()
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				errs <- fmt.Errorf("document %d: %w", pos, err)
+				return
+			}
+			pos++
+			if pos <= skip {
+				continue
+			}
+
+			docs = append(docs, doc)
+			if len(docs) == cfg.BatchSize && !send() {
+				return
+			}
+		}
+		if len(docs) > 0 {
+			send()
+		}
+	}()
+
+	wg.Wait()
+	close(errs)
+
+	if err := <-errs; err != nil {
+		return written, err
+	}
+	return written, ctx.Err()
+}
+
+// end import
+
+// Writes docs to coll in one unordered bulk write. If keys is not empty,
+// each document replaces the document with the same key values or is
+// inserted if none exists. The first resent documents are ones that a
+// previous run might have written.
+// begin write batch
+func writeBatch(ctx context.Context, coll *mongo.Collection, docs []bson.D, keys []string, resent int) (int64, error) {
+	models := make([]mongo.WriteModel, 0, len(docs))
+	for _, doc := range docs {
+		if len(keys) == 0 {
+			models = append(models, mongo.NewInsertOneModel().SetDocument(doc))
+			continue
+		}
+
+		filter := bson.D{}
+		for _, key := range keys {
+			value, ok := lookup(doc, key)
+			if !ok {
+				return 0, fmt.Errorf("document is missing upsert key %q", key)
+			}
+			filter = append(filter, bson.E{Key: key, Value: value})
+		}
+		models = append(models, mongo.NewReplaceOneModel().
+			SetFilter(filter).
+			SetReplacement(doc).
+			SetUpsert(true))
+	}
+
+	opts := options.BulkWrite().SetOrdered(false)
+	result, err := coll.BulkWrite(ctx, models, opts)
+
+	// Treats duplicate _id errors on resent documents as already imported,
+	// because a resumed import can resend documents from a batch that was
+	// only partly written. Other duplicates, such as a repeated _id in the
+	// file or a conflict on a unique secondary index, are errors.
+	var bwe mongo.BulkWriteException
+	if errors.As(err, &bwe) && bwe.WriteConcernError == nil {
+		for _, we := range bwe.WriteErrors {
+			if we.Index >= resent || !isDuplicateID(we.WriteError) {
+				return 0, err
+			}
+		}
+		err = nil
+	}
+	if err != nil {
+		return 0, err
+	}
+	return result.InsertedCount + result.UpsertedCount + result.ModifiedCount, nil
+}
+
+// end write batch
+
+// Reports whether we is a duplicate key error on the _id index
+func isDuplicateID(we mongo.WriteError) bool {
+	if we.Code != 11000 {
+		return false
+	}
+	// Servers since MongoDB 4.4 report the key pattern of the index
+	if pattern, err := we.Raw.LookupErr("keyPattern"); err == nil {
+		keys, ok := pattern.DocumentOK()
+		if !ok {
+			return false
+		}
+		elems, err := keys.Elements()
+		return err == nil && len(elems) == 1 && elems[0].Key() == "_id"
+	}
+	return strings.Contains(we.Message, " index: _id_ ")
+}
+
+// Returns the value of the top-level field key in doc
+func lookup(doc bson.D, key string) (interface{}, bool) {
+	for _, elem := range doc {
+		if elem.Key == key {
+			return elem.Value, true
+		}
+	}
+	return nil, false
+}
+
+// start-readers
+// Implemented by csvReader and by extjson.Reader for Extended JSON input
+type documentReader interface {
+	// Returns the next document, or io.EOF when the input is exhausted
+	Next() (bson.D, error)
+}
+
+// Reads CSV records that have a header row and converts each field by using
+// the coercion rules in types
+type csvReader struct {
+	reader       *csv.Reader
+	header       []string
+	types        map[string]string
+	ignoreBlanks bool
+}
+
+func newCSVReader(r io.Reader, types map[string]string, ignoreBlanks bool) (*csvReader, error) {
+	reader := csv.NewReader(r)
+	header, err := reader.Read()
+	if err != nil {
+		return nil, fmt.Errorf("reading CSV header: %w", err)
+	}
+	reader.FieldsPerRecord = len(header)
+	return &csvReader{reader: reader, header: header, types: types, ignoreBlanks: ignoreBlanks}, nil
+}
+
+func (r *csvReader) Next() (bson.D, error) {
+	record, err := r.reader.Read()
+	if err != nil {
+		return nil, err
+	}
+
+	doc := make(bson.D, 0, len(record))
+	for i, raw := range record {
+		field := r.header[i]
+		if raw == "" && r.ignoreBlanks {
+			continue
+		}
+
+		typ, ok := r.types[field]
+		if !ok {
+			typ = "auto"
+		}
+		value, err := coerce(typ, raw)
+		if err != nil {
+			return nil, fmt.Errorf("field %q: %w", field, err)
+		}
+		doc = append(doc, bson.E{Key: field, Value: value})
+	}
+	return doc, nil
+}
+
+// end-readers
+
+// begin coerce
+var errUnknownType = errors.New("unknown type")
+
+// Match decimal numbers without leading zeros, so that values such as ZIP
+// codes and "007" stay strings, and so that words such as "NaN" and "Inf"
+// are not numbers
+var (
+	autoInt   = regexp.MustCompile(`^-?(0|[1-9][0-9]*)$`)
+	autoFloat = regexp.MustCompile(`^-?(0|[1-9][0-9]*)(\.[0-9]+)?([eE][-+]?[0-9]+)?$`)
+)
+
+// Converts a CSV value to the BSON type named by typ. The "auto" type
+// stores integers, floating-point numbers and booleans as those types, and
+// everything else, including numbers with leading zeros, as a string.
+func coerce(typ, raw string) (interface{}, error) {
+	switch typ {
+	case "string":
+		return raw, nil
+	case "int":
+		i, err := strconv.ParseInt(raw, 10, 32)
+		return int32(i), err
+	case "long":
+		return strconv.ParseInt(raw, 10, 64)
+	case "double":
+		return strconv.ParseFloat(raw, 64)
+	case "decimal":
+		return bson.ParseDecimal128(raw)
+	case "bool":
+		return strconv.ParseBool(raw)
+	case "date":
+		return time.Parse(time.RFC3339, raw)
+	case "objectId":
+		return bson.ObjectIDFromHex(raw)
+	case "auto":
+		if autoInt.MatchString(raw) {
+			if i, err := strconv.ParseInt(raw, 10, 64); err == nil {
+				if int64(int32(i)) == i {
+					return int32(i), nil
+				}
+				return i, nil
+			}
+		}
+		if autoFloat.MatchString(raw) {
+			if f, err := strconv.ParseFloat(raw, 64); err == nil {
+				return f, nil
+			}
+		}
+		if raw == "true" || raw == "false" {
+			return raw == "true", nil
+		}
+		return raw, nil
+	default:
+		return nil, fmt.Errorf("%w %q: use string, int, long, double, decimal, bool, date, objectId or auto", errUnknownType, typ)
+	}
+}
+
+// end coerce
+
+// begin progress
+// Records how many documents from the start of the input have been written,
+// and how far the batches that were sent to the server reach. Batches can
+// finish out of order, so the committed count only advances past a batch
+// once every earlier batch has also finished. The file holds both counts,
+// separated by a space.
+type progressTracker struct {
+	mu        sync.Mutex
+	path      string
+	committed int64
+	sent      int64
+	nextSeq   int
+	pending   map[int]int64
+	// Position through which the previous run might have written documents
+	resumeThrough int64
+}
+
+func loadProgress(path string) (*progressTracker, error) {
+	p := &progressTracker{path: path, pending: map[int]int64{}}
+	if path == "" {
+		return p, nil
+	}
+
+	b, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return p, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	committed, sent, ok := strings.Cut(strings.TrimSpace(string(b)), " ")
+	if !ok {
+		return nil, fmt.Errorf("invalid progress file %s: want the committed and sent counts", path)
+	}
+	p.committed, err = strconv.ParseInt(committed, 10, 64)
+	if err == nil {
+		p.sent, err = strconv.ParseInt(sent, 10, 64)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("invalid progress file %s: %w", path, err)
+	}
+	p.resumeThrough = p.sent
+	return p, nil
+}
+
+// Records that a batch that reaches position end is about to be written
+func (p *progressTracker) started(end int64) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if end <= p.sent {
+		return nil
+	}
+	p.sent = end
+	return p.save()
+}
+
+// Marks batch seq as written through position end and saves the new count
+func (p *progressTracker) complete(seq int, end int64) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.pending[seq] = end
+	advanced := false
+	for {
+		end, ok := p.pending[p.nextSeq]
+		if !ok {
+			break
+		}
+		delete(p.pending, p.nextSeq)
+		p.committed = end
+		p.nextSeq++
+		advanced = true
+	}
+	if !advanced {
+		return nil
+	}
+	return p.save()
+}
+
+// Writes the counts to the progress file. Callers must hold p.mu.
+func (p *progressTracker) save() error {
+	if p.path == "" {
+		return nil
+	}
+
+	// Replaces the file atomically so that an interrupted write never
+	// leaves a partial count
+	tmp := p.path + ".tmp"
+	data := fmt.Sprintf("%d %d\n", p.committed, p.sent)
+	if err := os.WriteFile(tmp, []byte(data), 0o644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, p.path)
+}
+
+// end progress
@@ -0,0 +1,358 @@
+// Tests the import command without a MongoDB deployment. The Import tests run
+// against the fake server in tools/fakeserver. Run with:
+//
+//	go test import.go import_test.go
+package main
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+
+	"github.com/mongodb/docs-golang/source/includes/fundamentals/code-snippets/tools/fakeserver"
+)
+
+func TestCoerceAuto(t *testing.T) {
+	cases := []struct {
+		raw  string
+		want interface{}
+	}{
+		{"0", int32(0)},
+		{"42", int32(42)},
+		{"-17", int32(-17)},
+		{"3000000000", int64(3000000000)},
+		{"2.5", 2.5},
+		{"-0.25", -0.25},
+		{"1e3", 1000.0},
+		{"true", true},
+		{"false", false},
+		{"007", "007"},
+		{"02134", "02134"},
+		{"-01", "-01"},
+		{"00.5", "00.5"},
+		{"NaN", "NaN"},
+		{"Inf", "Inf"},
+		{"0x1F", "0x1F"},
+		{"TRUE", "TRUE"},
+		{"1_000", "1_000"},
+		{"", ""},
+		{"espresso", "espresso"},
+	}
+
+	for _, c := range cases {
+		got, err := coerce("auto", c.raw)
+		if err != nil {
+			t.Errorf("coerce(auto, %q): %v", c.raw, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, c.want) {
+			t.Errorf("coerce(auto, %q) = %#v, want %#v", c.raw, got, c.want)
+		}
+	}
+}
+
+func TestCoerceExplicitTypes(t *testing.T) {
+	if got, err := coerce("string", "007"); err != nil || got != "007" {
+		t.Errorf("coerce(string, 007) = %#v, %v", got, err)
+	}
+	if got, err := coerce("int", "007"); err != nil || got != int32(7) {
+		t.Errorf("coerce(int, 007) = %#v, %v", got, err)
+	}
+	if _, err := coerce("int", "3000000000"); err == nil {
+		t.Error("coerce(int, 3000000000) succeeded, want an out of range error")
+	}
+	if _, err := coerce("uuid", "x"); err == nil {
+		t.Error("coerce(uuid, x) succeeded, want an unknown type error")
+	}
+}
+
+func TestIsDuplicateID(t *testing.T) {
+	raw := func(d bson.D) bson.Raw {
+		b, err := bson.Marshal(d)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return b
+	}
+
+	cases := []struct {
+		name string
+		we   mongo.WriteError
+		want bool
+	}{
+		{"_id key pattern", mongo.WriteError{
+			Code: 11000,
+			Raw:  raw(bson.D{{"code", 11000}, {"keyPattern", bson.D{{"_id", 1}}}}),
+		}, true},
+		{"unique secondary index", mongo.WriteError{
+			Code: 11000,
+			Raw:  raw(bson.D{{"code", 11000}, {"keyPattern", bson.D{{"email", 1}}}}),
+		}, false},
+		{"compound index that includes _id", mongo.WriteError{
+			Code: 11000,
+			Raw:  raw(bson.D{{"code", 11000}, {"keyPattern", bson.D{{"_id", 1}, {"v", 1}}}}),
+		}, false},
+		{"_id index in the message", mongo.WriteError{
+			Code:    11000,
+			Message: "E11000 duplicate key error collection: db.c index: _id_ dup key: { _id: 1 }",
+		}, true},
+		{"secondary index in the message", mongo.WriteError{
+			Code:    11000,
+			Message: "E11000 duplicate key error collection: db.c index: email_1 dup key: { email: \"a\" }",
+		}, false},
+		{"other error", mongo.WriteError{Code: 121, Message: "Document failed validation"}, false},
+	}
+
+	for _, c := range cases {
+		if got := isDuplicateID(c.we); got != c.want {
+			t.Errorf("%s: got %v, want %v", c.name, got, c.want)
+		}
+	}
+}
+
+func TestProgressResume(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "import.progress")
+
+	p, err := loadProgress(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, end := range []int64{10, 20, 30} {
+		if err = p.started(end); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// The second batch finishes first, so the committed count waits for
+	// the first batch
+	if err = p.complete(1, 20); err != nil {
+		t.Fatal(err)
+	}
+	if p.committed != 0 {
+		t.Errorf("committed %d after an out of order batch, want 0", p.committed)
+	}
+	if err = p.complete(0, 10); err != nil {
+		t.Fatal(err)
+	}
+
+	// Simulates a run that stops while the third batch is in flight
+	b, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := string(b); got != "20 30\n" {
+		t.Errorf("progress file holds %q, want %q", got, "20 30\n")
+	}
+
+	resumed, err := loadProgress(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resumed.committed != 20 || resumed.resumeThrough != 30 {
+		t.Errorf("resumed with committed %d and resumeThrough %d, want 20 and 30", resumed.committed, resumed.resumeThrough)
+	}
+}
+
+// Starts a fake server and returns a collection on it
+func startServer(t *testing.T) *mongo.Collection {
+	t.Helper()
+	srv, err := fakeserver.Start()
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { srv.Close() })
+
+	client, err := mongo.Connect(options.Client().ApplyURI(srv.URI()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { client.Disconnect(context.Background()) })
+	return client.Database("db").Collection("courses")
+}
+
+// Writes content to a file in a temporary directory and returns its path
+func writeInput(t *testing.T, name, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+// Returns the documents in coll sorted by sortKey, without their _id
+// unless keepID is set
+func documents(t *testing.T, coll *mongo.Collection, sortKey string, keepID bool) []bson.D {
+	t.Helper()
+	opts := options.Find().SetSort(bson.D{{sortKey, 1}})
+	if !keepID {
+		opts.SetProjection(bson.D{{"_id", 0}})
+	}
+	cursor, err := coll.Find(context.Background(), bson.D{}, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var docs []bson.D
+	if err = cursor.All(context.Background(), &docs); err != nil {
+		t.Fatal(err)
+	}
+	return docs
+}
+
+func TestImportCSV(t *testing.T) {
+	coll := startServer(t)
+	cfg := Config{
+		File: writeInput(t, "courses.csv", `title,enrollment,startDate,room
+World Fiction,35,2024-01-15T00:00:00Z,007
+Abstract Algebra,60,2024-02-01T00:00:00Z,
+Modern Poetry,12,2024-01-20T00:00:00Z,101
+Plate Tectonics,45,2024-03-01T00:00:00Z,
+Ancient Greece,100,2024-02-15T00:00:00Z,202
+`),
+		Format:       "csv",
+		Workers:      2,
+		BatchSize:    2,
+		Types:        map[string]string{"enrollment": "long", "startDate": "date"},
+		IgnoreBlanks: true,
+	}
+
+	imported, err := Import(context.Background(), coll, cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if imported != 5 {
+		t.Errorf("imported %d documents, want 5", imported)
+	}
+
+	date := func(s string) bson.DateTime {
+		tm, err := time.Parse(time.RFC3339, s)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return bson.NewDateTimeFromTime(tm)
+	}
+	want := []bson.D{
+		{{"title", "Abstract Algebra"}, {"enrollment", int64(60)}, {"startDate", date("2024-02-01T00:00:00Z")}},
+		{{"title", "Ancient Greece"}, {"enrollment", int64(100)}, {"startDate", date("2024-02-15T00:00:00Z")}, {"room", int32(202)}},
+		{{"title", "Modern Poetry"}, {"enrollment", int64(12)}, {"startDate", date("2024-01-20T00:00:00Z")}, {"room", int32(101)}},
+		{{"title", "Plate Tectonics"}, {"enrollment", int64(45)}, {"startDate", date("2024-03-01T00:00:00Z")}},
+		{{"title", "World Fiction"}, {"enrollment", int64(35)}, {"startDate", date("2024-01-15T00:00:00Z")}, {"room", "007"}},
+	}
+	if got := documents(t, coll, "title", false); !reflect.DeepEqual(got, want) {
+		t.Errorf("got documents\n%v\nwant\n%v", got, want)
+	}
+}
+
+// Imports a file, then a second file whose documents replace the documents
+// with the same title
+func TestImportUpsert(t *testing.T) {
+	coll := startServer(t)
+	cfg := Config{
+		File: writeInput(t, "courses.json", `{"title": "World Fiction", "enrollment": 35}
+{"title": "Abstract Algebra", "enrollment": 60}
+{"title": "Modern Poetry", "enrollment": 12}
+`),
+		Format:     "ndjson",
+		Workers:    1,
+		BatchSize:  10,
+		UpsertKeys: []string{"title"},
+	}
+	if _, err := Import(context.Background(), coll, cfg); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg.File = writeInput(t, "changes.json", `[
+	{"title": "Modern Poetry", "enrollment": 20, "full": true},
+	{"title": "Plate Tectonics", "enrollment": 45}
+]`)
+	imported, err := Import(context.Background(), coll, cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if imported != 2 {
+		t.Errorf("imported %d documents, want one replaced and one inserted", imported)
+	}
+
+	want := []bson.D{
+		{{"title", "Abstract Algebra"}, {"enrollment", int32(60)}},
+		{{"title", "Modern Poetry"}, {"enrollment", int32(20)}, {"full", true}},
+		{{"title", "Plate Tectonics"}, {"enrollment", int32(45)}},
+		{{"title", "World Fiction"}, {"enrollment", int32(35)}},
+	}
+	if got := documents(t, coll, "title", false); !reflect.DeepEqual(got, want) {
+		t.Errorf("got documents\n%v\nwant\n%v", got, want)
+	}
+
+	cfg.File = writeInput(t, "missing.json", `{"enrollment": 5}`)
+	if _, err = Import(context.Background(), coll, cfg); err == nil {
+		t.Error("importing a document without the upsert key succeeded")
+	}
+}
+
+// Resumes an import that stopped after committing two documents while a
+// batch that reaches the fourth document was in flight and wrote the third
+func TestImportResume(t *testing.T) {
+	coll := startServer(t)
+	if _, err := coll.InsertOne(context.Background(), bson.D{{"_id", int32(3)}, {"title", "Modern Poetry"}}); err != nil {
+		t.Fatal(err)
+	}
+	progressFile := writeInput(t, "import.progress", "2 4\n")
+	cfg := Config{
+		File: writeInput(t, "courses.json", `{"_id": 1, "title": "World Fiction"}
+{"_id": 2, "title": "Abstract Algebra"}
+{"_id": 3, "title": "Modern Poetry"}
+{"_id": 4, "title": "Plate Tectonics"}
+{"_id": 5, "title": "Ancient Greece"}
+{"_id": 6, "title": "Romantic Era Music"}
+`),
+		Format:       "ndjson",
+		Workers:      2,
+		BatchSize:    2,
+		ProgressFile: progressFile,
+	}
+
+	imported, err := Import(context.Background(), coll, cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if imported != 3 {
+		t.Errorf("imported %d documents, want the fourth through sixth", imported)
+	}
+	var ids []interface{}
+	for _, doc := range documents(t, coll, "_id", true) {
+		ids = append(ids, doc[0].Value)
+	}
+	if want := []interface{}{int32(3), int32(4), int32(5), int32(6)}; !reflect.DeepEqual(ids, want) {
+		t.Errorf("got _id values %v, want %v", ids, want)
+	}
+	b, err := os.ReadFile(progressFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := string(b); got != "6 6\n" {
+		t.Errorf("progress file holds %q, want %q", got, "6 6\n")
+	}
+
+	// A duplicate _id past the documents that the previous run sent is an
+	// error
+	if err = os.WriteFile(progressFile, []byte("0 0\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = Import(context.Background(), coll, cfg); err == nil {
+		t.Error("importing duplicate _id values succeeded")
+	}
+
+	if err = os.WriteFile(progressFile, []byte("15\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = loadProgress(progressFile); err == nil {
+		t.Error("loading a progress file without the sent count succeeded")
+	}
+}
@@ -0,0 +1,91 @@
+// Package snippettest runs the main function of a snippet against the fake
+// server in tools/fakeserver, so that the snippet can be tested without a
+// MongoDB deployment.
+//
+// Example:
+//
+//	func TestSnippet(t *testing.T) {
+//		snippettest.Equal(t, snippettest.Run(t, main), golden)
+//	}
+package snippettest
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/mongodb/docs-golang/source/includes/fundamentals/code-snippets/tools/fakeserver"
+)
+
+// Starts a fake server, sets MONGODB_URI to its address, and runs main.
+// Returns what main wrote to standard output. Fails the test if main panics.
+func Run(t *testing.T, main func()) string {
+	t.Helper()
+	srv, err := fakeserver.Start()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer srv.Close()
+	t.Setenv("MONGODB_URI", srv.URI())
+
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var out bytes.Buffer
+	copied := make(chan error, 1)
+	go func() {
+		_, err := io.Copy(&out, r)
+		r.Close()
+		copied <- err
+	}()
+
+	stdout := os.Stdout
+	os.Stdout = w
+	panicked := func() (p interface{}) {
+		defer func() { p = recover() }()
+		main()
+		return nil
+	}()
+	os.Stdout = stdout
+	w.Close()
+	if err := <-copied; err != nil {
+		t.Fatal(err)
+	}
+
+	if panicked != nil {
+		t.Fatalf("main panicked: %v\noutput:\n%s", panicked, out.String())
+	}
+	return out.String()
+}
+
+// Compares the output of a snippet with golden output line by line, ignoring
+// trailing spaces, and reports the first line that differs
+func Equal(t *testing.T, got, want string) {
+	t.Helper()
+	gotLines, wantLines := lines(got), lines(want)
+	for i := 0; i < len(gotLines) || i < len(wantLines); i++ {
+		var g, w string
+		if i < len(gotLines) {
+			g = gotLines[i]
+		}
+		if i < len(wantLines) {
+			w = wantLines[i]
+		}
+		if g != w {
+			t.Errorf("line %d: got %q, want %q\nfull output:\n%s", i+1, g, w, got)
+			return
+		}
+	}
+}
+
+// Splits output into lines without trailing spaces or a trailing empty line
+func lines(s string) []string {
+	out := strings.Split(strings.TrimRight(s, " \n"), "\n")
+	for i := range out {
+		out[i] = strings.TrimRight(out[i], " ")
+	}
+	return out
+}
@@ -0,0 +1,242 @@
+// Stores UUID values as BSON binary subtype 4 with a registered codec by
+// using the Go driver
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"reflect"
+	"strings"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+)
+
+// start-uuid-type
+// Holds the 16 bytes of an RFC 9562 UUID in network byte order
+type UUID [16]byte
+
+// Returns a random version 4 UUID
+func NewUUID() UUID {
+	var u UUID
+	if _, err := rand.Read(u[:]); err != nil {
+		panic(err)
+	}
+	u[6] = u[6]&0x0f | 0x40 // version 4
+	u[8] = u[8]&0x3f | 0x80 // RFC 9562 variant
+	return u
+}
+
+// Parses a UUID in the form "xxxxxxxx-xxxx-xxxx-xxxx-xxxxxxxxxxxx"
+func ParseUUID(s string) (UUID, error) {
+	var u UUID
+	if len(s) != 36 || s[8] != '-' || s[13] != '-' || s[18] != '-' || s[23] != '-' {
+		return u, fmt.Errorf("invalid UUID %q", s)
+	}
+	if _, err := hex.Decode(u[:], []byte(strings.ReplaceAll(s, "-", ""))); err != nil {
+		return u, fmt.Errorf("invalid UUID %q: %w", s, err)
+	}
+	return u, nil
+}
+
+func (u UUID) String() string {
+	h := hex.EncodeToString(u[:])
+	return h[0:8] + "-" + h[8:12] + "-" + h[12:16] + "-" + h[16:20] + "-" + h[20:32]
+}
+
+// end-uuid-type
+
+// start-uuid-codec
+var tUUID = reflect.TypeOf(UUID{})
+
+// Writes a UUID as binary subtype 4
+func encodeUUID(_ bson.EncodeContext, vw bson.ValueWriter, val reflect.Value) error {
+	if val.Type() != tUUID {
+		return bson.ValueEncoderError{Name: "encodeUUID", Types: []reflect.Type{tUUID}, Received: val}
+	}
+	u := val.Interface().(UUID)
+	return vw.WriteBinaryWithSubtype(u[:], bson.TypeBinaryUUID)
+}
+
+// Reads binary subtype 4 into a UUID. Rejects the legacy subtype 3, because
+// drivers for different languages wrote its bytes in different orders.
+func decodeUUID(_ bson.DecodeContext, vr bson.ValueReader, val reflect.Value) error {
+	if !val.CanSet() || val.Type() != tUUID {
+		return bson.ValueDecoderError{Name: "decodeUUID", Types: []reflect.Type{tUUID}, Received: val}
+	}
+
+	switch vr.Type() {
+	case bson.TypeNull:
+		val.Set(reflect.Zero(tUUID))
+		return vr.ReadNull()
+	case bson.TypeBinary:
+	default:
+		return fmt.Errorf("cannot decode %v into a UUID", vr.Type())
+	}
+
+	data, subtype, err := vr.ReadBinary()
+	if err != nil {
+		return err
+	}
+	if subtype == bson.TypeBinaryUUIDOld {
+		return errors.New("cannot decode a legacy subtype 3 UUID, whose byte order depends on the driver that wrote it")
+	}
+	if subtype != bson.TypeBinaryUUID || len(data) != 16 {
+		return fmt.Errorf("cannot decode binary subtype %d of length %d into a UUID", subtype, len(data))
+	}
+
+	var u UUID
+	copy(u[:], data)
+	val.Set(reflect.ValueOf(u))
+	return nil
+}
+
+// Returns a registry that encodes and decodes UUID values
+func newUUIDRegistry() *bson.Registry {
+	reg := bson.NewRegistry()
+	reg.RegisterTypeEncoder(tUUID, bson.ValueEncoderFunc(encodeUUID))
+	reg.RegisterTypeDecoder(tUUID, bson.ValueDecoderFunc(decodeUUID))
+	return reg
+}
+
+// end-uuid-codec
+
+// start-account-struct
+type Account struct {
+	ID    UUID   `bson:"_id"`
+	Owner string `bson:"owner"`
+	// Refers to another account by its UUID
+	ReferredBy UUID `bson:"referred_by"`
+}
+
+// end-account-struct
+
+func main() {
+	reg := newUUIDRegistry()
+
+	fmt.Println("Extended JSON:")
+	{
+		// Encodes an account as canonical Extended JSON, which represents
+		// subtype 4 as $binary, and decodes Extended JSON that uses the
+		// $uuid shorthand
+		// begin extjson
+		id, err := ParseUUID("3b241101-e2bb-4255-8caf-4136c566a962")
+		if err != nil {
+			panic(err)
+		}
+		referrer, err := ParseUUID("6f1a5c3e-2d4b-4e8f-9a7c-1b2d3e4f5a6b")
+		if err != nil {
+			panic(err)
+		}
+		account := Account{ID: id, Owner: "Sam Lee", ReferredBy: referrer}
+
+		var buf bytes.Buffer
+		enc := bson.NewEncoder(bson.NewExtJSONValueWriter(&buf, true, false))
+		enc.SetRegistry(reg)
+		if err = enc.Encode(account); err != nil {
+			panic(err)
+		}
+		encoded := strings.TrimSpace(buf.String())
+		fmt.Printf("Encoded: %s\n", encoded)
+
+		input := `{"_id": {"$uuid": "3b241101-e2bb-4255-8caf-4136c566a962"}, "owner": "Sam Lee",` +
+			` "referred_by": {"$uuid": "6f1a5c3e-2d4b-4e8f-9a7c-1b2d3e4f5a6b"}}`
+		vr, err := bson.NewExtJSONValueReader(strings.NewReader(input), false)
+		if err != nil {
+			panic(err)
+		}
+		dec := bson.NewDecoder(vr)
+		dec.SetRegistry(reg)
+
+		var decoded Account
+		if err = dec.Decode(&decoded); err != nil {
+			panic(err)
+		}
+		fmt.Printf("Decoded _id: %v\n", decoded.ID)
+		// end extjson
+	}
+
+	var uri string
+	if uri = os.Getenv("MONGODB_URI"); uri == "" {
+		log.Fatal("You must set your 'MONGODB_URI' environment variable. See\n\t https://www.mongodb.com/docs/drivers/go/current/usage-examples/")
+	}
+
+	// Registers the UUID codec for every operation on the client
+	// begin client registry
+	client, err := mongo.Connect(options.Client().ApplyURI(uri).SetRegistry(reg))
+	// end client registry
+	if err != nil {
+		panic(err)
+	}
+	defer func() {
+		if err = client.Disconnect(context.TODO()); err != nil {
+			panic(err)
+		}
+	}()
+
+	coll := client.Database("db").Collection("accounts")
+	_ = coll.Drop(context.TODO())
+
+	fmt.Println("\nQuery by UUID:")
+	{
+		// begin query by uuid
+		referrer := Account{ID: NewUUID(), Owner: "Ana Ruiz"}
+		account := Account{ID: NewUUID(), Owner: "Sam Lee", ReferredBy: referrer.ID}
+
+		if _, err = coll.InsertMany(context.TODO(), []interface{}{referrer, account}); err != nil {
+			panic(err)
+		}
+
+		// The filter encodes the UUID as subtype 4 with the client registry
+		var found Account
+		err = coll.FindOne(context.TODO(), bson.D{{"referred_by", referrer.ID}}).Decode(&found)
+		if err != nil {
+			panic(err)
+		}
+		fmt.Printf("%s (%v) was referred by %v\n", found.Owner, found.ID, found.ReferredBy)
+		// end query by uuid
+	}
+
+	fmt.Println("\nLegacy Subtype 3:")
+	{
+		// Inserts the same UUID as both subtypes. The legacy Java driver
+		// wrote subtype 3 with each 8-byte half reversed.
+		// begin legacy subtype
+		id := NewUUID()
+		javaLegacy := make([]byte, 16)
+		for i := 0; i < 8; i++ {
+			javaLegacy[i] = id[7-i]
+			javaLegacy[8+i] = id[15-i]
+		}
+
+		docs := []interface{}{
+			bson.D{{"owner", "standard"}, {"ref", id}},
+			bson.D{{"owner", "legacy"}, {"ref", bson.Binary{Subtype: bson.TypeBinaryUUIDOld, Data: javaLegacy}}},
+		}
+		if _, err = coll.InsertMany(context.TODO(), docs); err != nil {
+			panic(err)
+		}
+
+		// A subtype 4 filter does not match the subtype 3 value
+		count, err := coll.CountDocuments(context.TODO(), bson.D{{"ref", id}})
+		if err != nil {
+			panic(err)
+		}
+		fmt.Printf("Documents matching subtype 4: %d\n", count)
+
+		// Decoding the subtype 3 value into a UUID fails
+		var legacy struct {
+			Ref UUID `bson:"ref"`
+		}
+		err = coll.FindOne(context.TODO(), bson.D{{"owner", "legacy"}}).Decode(&legacy)
+		fmt.Printf("Decoding subtype 3: %v\n", err)
+		// end legacy subtype
+	}
+}
@@ -0,0 +1,130 @@
+// Tests the UUID codec offline and on the fake server. Run with:
+//
+//	go test uuid.go uuid_test.go
+package main
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+
+	"github.com/mongodb/docs-golang/source/includes/fundamentals/code-snippets/tools/fakeserver"
+)
+
+// Encodes an account as canonical Extended JSON, compares it with golden
+// output, and decodes the $uuid shorthand back into the same account
+func TestExtJSON(t *testing.T) {
+	reg := newUUIDRegistry()
+	id, err := ParseUUID("3b241101-e2bb-4255-8caf-4136c566a962")
+	if err != nil {
+		t.Fatal(err)
+	}
+	referrer, err := ParseUUID("6f1a5c3e-2d4b-4e8f-9a7c-1b2d3e4f5a6b")
+	if err != nil {
+		t.Fatal(err)
+	}
+	account := Account{ID: id, Owner: "Sam Lee", ReferredBy: referrer}
+
+	var buf bytes.Buffer
+	enc := bson.NewEncoder(bson.NewExtJSONValueWriter(&buf, true, false))
+	enc.SetRegistry(reg)
+	if err = enc.Encode(account); err != nil {
+		t.Fatal(err)
+	}
+	golden := `{"_id":{"$binary":{"base64":"OyQRAeK7QlWMr0E2xWapYg==","subType":"04"}},"owner":"Sam Lee",` +
+		`"referred_by":{"$binary":{"base64":"bxpcPi1LTo+afBstPk9aaw==","subType":"04"}}}`
+	if encoded := strings.TrimSpace(buf.String()); encoded != golden {
+		t.Errorf("encoded account differs from golden output\ngot:  %s\nwant: %s", encoded, golden)
+	}
+
+	input := `{"_id": {"$uuid": "3b241101-e2bb-4255-8caf-4136c566a962"}, "owner": "Sam Lee",` +
+		` "referred_by": {"$uuid": "6f1a5c3e-2d4b-4e8f-9a7c-1b2d3e4f5a6b"}}`
+	vr, err := bson.NewExtJSONValueReader(strings.NewReader(input), false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	dec := bson.NewDecoder(vr)
+	dec.SetRegistry(reg)
+	var decoded Account
+	if err = dec.Decode(&decoded); err != nil {
+		t.Fatal(err)
+	}
+	if decoded != account {
+		t.Errorf("decoded account %+v differs from %+v", decoded, account)
+	}
+}
+
+// Returns a collection on the fake server whose client uses the UUID codec
+func accounts(t *testing.T) *mongo.Collection {
+	t.Helper()
+	srv, err := fakeserver.Start()
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { srv.Close() })
+
+	client, err := mongo.Connect(options.Client().ApplyURI(srv.URI()).SetRegistry(newUUIDRegistry()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { client.Disconnect(context.Background()) })
+	return client.Database("db").Collection("accounts")
+}
+
+func TestQueryByUUID(t *testing.T) {
+	coll := accounts(t)
+	ctx := context.Background()
+	referrer := Account{ID: NewUUID(), Owner: "Ana Ruiz"}
+	account := Account{ID: NewUUID(), Owner: "Sam Lee", ReferredBy: referrer.ID}
+	if _, err := coll.InsertMany(ctx, []interface{}{referrer, account}); err != nil {
+		t.Fatal(err)
+	}
+
+	var found Account
+	if err := coll.FindOne(ctx, bson.D{{"referred_by", referrer.ID}}).Decode(&found); err != nil {
+		t.Fatal(err)
+	}
+	if found != account {
+		t.Errorf("found %+v, want %+v", found, account)
+	}
+}
+
+// Checks that a subtype 4 filter does not match the same UUID stored as
+// subtype 3, and that decoding the subtype 3 value fails
+func TestLegacySubtype(t *testing.T) {
+	coll := accounts(t)
+	ctx := context.Background()
+	id := NewUUID()
+	javaLegacy := make([]byte, 16)
+	for i := 0; i < 8; i++ {
+		javaLegacy[i] = id[7-i]
+		javaLegacy[8+i] = id[15-i]
+	}
+	docs := []interface{}{
+		bson.D{{"owner", "standard"}, {"ref", id}},
+		bson.D{{"owner", "legacy"}, {"ref", bson.Binary{Subtype: bson.TypeBinaryUUIDOld, Data: javaLegacy}}},
+	}
+	if _, err := coll.InsertMany(ctx, docs); err != nil {
+		t.Fatal(err)
+	}
+
+	count, err := coll.CountDocuments(ctx, bson.D{{"ref", id}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if count != 1 {
+		t.Errorf("got %d documents matching subtype 4, want 1", count)
+	}
+
+	var legacy struct {
+		Ref UUID `bson:"ref"`
+	}
+	if err = coll.FindOne(ctx, bson.D{{"owner", "legacy"}}).Decode(&legacy); err == nil {
+		t.Error("decoding a subtype 3 value into a UUID succeeded")
+	}
+}
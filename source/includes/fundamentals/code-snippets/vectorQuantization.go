@@ -0,0 +1,243 @@
+// Compares float32, int8, and packed bit BSON vectors by storage size and
+// search recall by using the Go driver
+//
+// This example runs without a MongoDB deployment. It generates vectors
+// locally, encodes them in each BSON vector format, and measures the results.
+package main
+
+import (
+	"fmt"
+	"log"
+	"math"
+	"math/rand"
+	"sort"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+const (
+	dimensions = 384
+	numDocs    = 2000
+	numQueries = 50
+	topK       = 10
+)
+
+func main() {
+	fmt.Println("Round Trip:")
+	{
+		// Converts a float32 vector to each format, converts each format to
+		// a BSON binary value, and decodes the binary value
+		// begin round trip
+		floats := []float32{0.12, -0.5, 0.33, 0.9, -0.07, 0.0, -0.81, 0.44, 0.25, -0.3}
+
+		float32Vector := bson.NewVector(floats)
+		int8Vector := bson.NewVector(quantizeInt8(floats, 1))
+		bits, padding := quantizeBits(floats)
+		bitVector, err := bson.NewPackedBitVector(bits, padding)
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		for _, v := range []bson.Vector{float32Vector, int8Vector, bitVector} {
+			decoded, err := bson.NewVectorFromBinary(v.Binary())
+			if err != nil {
+				log.Fatal(err)
+			}
+
+			switch decoded.Type() {
+			case bson.Float32Vector:
+				fmt.Printf("float32: %v\n", decoded.Float32())
+			case bson.Int8Vector:
+				fmt.Printf("int8: %v\n", decoded.Int8())
+			case bson.PackedBitVector:
+				data, padding := decoded.PackedBit()
+				fmt.Printf("packed bit: %08b (padding %d)\n", data, padding)
+			}
+		}
+		// end round trip
+	}
+
+	fmt.Println("\nStorage Size and Recall:")
+	{
+		docs, queries := generateVectors(rand.New(rand.NewSource(1)))
+
+		// Uses one scale for every vector so that int8 dot products remain
+		// comparable across documents
+		var maxAbs float32
+		for _, doc := range docs {
+			for _, v := range doc {
+				maxAbs = float32(math.Max(float64(maxAbs), math.Abs(float64(v))))
+			}
+		}
+
+		// begin storage size
+		sizes := map[string]int{}
+		for _, doc := range docs {
+			bits, padding := quantizeBits(doc)
+			bitVector, err := bson.NewPackedBitVector(bits, padding)
+			if err != nil {
+				log.Fatal(err)
+			}
+
+			formats := map[string]bson.Vector{
+				"float32":    bson.NewVector(doc),
+				"int8":       bson.NewVector(quantizeInt8(doc, maxAbs)),
+				"packed bit": bitVector,
+			}
+			for name, v := range formats {
+				b, err := bson.Marshal(bson.D{{"embedding", v.Binary()}})
+				if err != nil {
+					log.Fatal(err)
+				}
+				sizes[name] += len(b)
+			}
+		}
+		// end storage size
+
+		// Compares the nearest neighbors found with each format to the
+		// nearest neighbors found with the original float32 values
+		recall := map[string]float64{}
+		for _, q := range queries {
+			truth := topIDs(len(docs), func(i int) float64 { return dotFloat32(q, docs[i]) })
+
+			qInt8 := quantizeInt8(q, maxAbs)
+			int8Results := topIDs(len(docs), func(i int) float64 {
+				return float64(dotInt8(qInt8, quantizeInt8(docs[i], maxAbs)))
+			})
+
+			qBits, _ := quantizeBits(q)
+			bitResults := topIDs(len(docs), func(i int) float64 {
+				docBits, _ := quantizeBits(docs[i])
+				return -float64(hamming(qBits, docBits))
+			})
+
+			recall["float32"] += overlap(truth, truth)
+			recall["int8"] += overlap(truth, int8Results)
+			recall["packed bit"] += overlap(truth, bitResults)
+		}
+
+		for _, name := range []string{"float32", "int8", "packed bit"} {
+			fmt.Printf("%-10s  %8d bytes  %5.1f%% of float32  recall@%d %.2f\n",
+				name, sizes[name], 100*float64(sizes[name])/float64(sizes["float32"]),
+				topK, recall[name]/numQueries)
+		}
+	}
+}
+
+// begin quantize
+// Scales each value from [-maxAbs, maxAbs] to [-127, 127]. A maxAbs of 0,
+// which means that every value is 0, produces a vector of zeros.
+func quantizeInt8(v []float32, maxAbs float32) []int8 {
+	out := make([]int8, len(v))
+	if maxAbs == 0 {
+		return out
+	}
+	for i, x := range v {
+		q := math.Round(float64(x / maxAbs * 127))
+		out[i] = int8(math.Max(-127, math.Min(127, q)))
+	}
+	return out
+}
+
+// Stores one bit for each value, set when the value is positive. Returns the
+// packed bytes and the number of unused bits in the last byte.
+func quantizeBits(v []float32) ([]byte, uint8) {
+	out := make([]byte, (len(v)+7)/8)
+	for i, x := range v {
+		if x > 0 {
+			out[i/8] |= 0x80 >> (i % 8)
+		}
+	}
+	return out, uint8((8 - len(v)%8) % 8)
+}
+
+// end quantize
+
+// Generates document and query vectors grouped around a few random centers,
+// which resembles the structure of real embeddings more than uniform noise
+func generateVectors(r *rand.Rand) (docs, queries [][]float32) {
+	centers := make([][]float32, 20)
+	for i := range centers {
+		centers[i] = randomVector(r, nil, 1)
+	}
+
+	for i := 0; i < numDocs; i++ {
+		docs = append(docs, randomVector(r, centers[r.Intn(len(centers))], 0.6))
+	}
+	for i := 0; i < numQueries; i++ {
+		queries = append(queries, randomVector(r, centers[r.Intn(len(centers))], 0.6))
+	}
+	return docs, queries
+}
+
+// Returns a unit vector near center, or a random unit vector if center is nil
+func randomVector(r *rand.Rand, center []float32, noise float64) []float32 {
+	v := make([]float32, dimensions)
+	var norm float64
+	for i := range v {
+		x := r.NormFloat64() * noise
+		if center != nil {
+			x += float64(center[i])
+		}
+		v[i] = float32(x)
+		norm += x * x
+	}
+	for i := range v {
+		v[i] /= float32(math.Sqrt(norm))
+	}
+	return v
+}
+
+// Returns the indexes of the topK highest scores
+func topIDs(n int, score func(i int) float64) []int {
+	ids := make([]int, n)
+	scores := make([]float64, n)
+	for i := range ids {
+		ids[i] = i
+		scores[i] = score(i)
+	}
+	sort.SliceStable(ids, func(a, b int) bool { return scores[ids[a]] > scores[ids[b]] })
+	return ids[:topK]
+}
+
+// Returns the fraction of want that also appears in got
+func overlap(want, got []int) float64 {
+	seen := map[int]bool{}
+	for _, id := range want {
+		seen[id] = true
+	}
+	var n int
+	for _, id := range got {
+		if seen[id] {
+			n++
+		}
+	}
+	return float64(n) / float64(len(want))
+}
+
+func dotFloat32(a, b []float32) float64 {
+	var sum float64
+	for i := range a {
+		sum += float64(a[i]) * float64(b[i])
+	}
+	return sum
+}
+
+func dotInt8(a, b []int8) int64 {
+	var sum int64
+	for i := range a {
+		sum += int64(a[i]) * int64(b[i])
+	}
+	return sum
+}
+
+func hamming(a, b []byte) int {
+	var n int
+	for i := range a {
+		x := a[i] ^ b[i]
+		for ; x != 0; x &= x - 1 {
+			n++
+		}
+	}
+	return n
+}
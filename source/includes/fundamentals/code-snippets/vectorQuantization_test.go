@@ -0,0 +1,143 @@
+// Tests the quantization helpers and the BSON vector round trips of the
+// vector quantization example. Run with:
+//
+//	go test vectorQuantization.go vectorQuantization_test.go
+package main
+
+import (
+	"bytes"
+	"math/rand"
+	"reflect"
+	"testing"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+func TestQuantizeInt8(t *testing.T) {
+	got := quantizeInt8([]float32{1, -1, 0.5, -0.25, 0, 2, -3}, 1)
+	want := []int8{127, -127, 64, -32, 0, 127, -127}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+
+	// Dividing by a maxAbs of 0 would produce NaN, and the Go specification
+	// leaves the int8 value of NaN to the implementation
+	if got := quantizeInt8([]float32{0, 0, 0}, 0); !reflect.DeepEqual(got, []int8{0, 0, 0}) {
+		t.Errorf("got %v for an all-zero vector, want zeros", got)
+	}
+}
+
+func TestQuantizeBits(t *testing.T) {
+	cases := []struct {
+		values  []float32
+		bits    []byte
+		padding uint8
+	}{
+		{[]float32{}, []byte{}, 0},
+		{[]float32{1, -1, 1}, []byte{0b10100000}, 5},
+		{[]float32{1, 0, -1, 1, 1, 1, -1, 1}, []byte{0b10011101}, 0},
+		{[]float32{-1, -1, -1, -1, -1, -1, -1, -1, 1}, []byte{0b00000000, 0b10000000}, 7},
+	}
+	for _, c := range cases {
+		bits, padding := quantizeBits(c.values)
+		if !bytes.Equal(bits, c.bits) || padding != c.padding {
+			t.Errorf("quantizeBits(%v) = %08b, %d, want %08b, %d", c.values, bits, padding, c.bits, c.padding)
+		}
+	}
+}
+
+// Checks that a vector in each format survives a round trip through BSON
+func TestRoundTrip(t *testing.T) {
+	floats := randomVector(rand.New(rand.NewSource(1)), nil, 1)
+	bits, padding := quantizeBits(floats[:13])
+	bitVector, err := bson.NewPackedBitVector(bits, padding)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, v := range []bson.Vector{bson.NewVector(floats), bson.NewVector(quantizeInt8(floats, 1)), bitVector} {
+		b, err := bson.Marshal(bson.D{{"embedding", v}})
+		if err != nil {
+			t.Fatal(err)
+		}
+		var doc struct {
+			Embedding bson.Vector `bson:"embedding"`
+		}
+		if err = bson.Unmarshal(b, &doc); err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(doc.Embedding, v) {
+			t.Errorf("%v vector changed in a round trip", v.Type())
+		}
+	}
+}
+
+func TestVectorErrors(t *testing.T) {
+	cases := []struct {
+		name    string
+		build   func() (bson.Vector, error)
+		wantErr bool
+	}{
+		{"packed bit with no padding", func() (bson.Vector, error) {
+			return bson.NewPackedBitVector([]byte{0b10110000}, 0)
+		}, false},
+		{"packed bit with 4 bits of padding", func() (bson.Vector, error) {
+			return bson.NewPackedBitVector([]byte{0b10110000}, 4)
+		}, false},
+		{"packed bit with padding larger than 7", func() (bson.Vector, error) {
+			return bson.NewPackedBitVector([]byte{0b10110000}, 8)
+		}, true},
+		{"packed bit with padding but no data", func() (bson.Vector, error) {
+			return bson.NewPackedBitVector(nil, 1)
+		}, true},
+		{"binary that is not a vector subtype", func() (bson.Vector, error) {
+			return bson.NewVectorFromBinary(bson.Binary{Subtype: bson.TypeBinaryGeneric, Data: []byte{0x03, 0x00}})
+		}, true},
+		{"binary with only a type byte", func() (bson.Vector, error) {
+			return bson.NewVectorFromBinary(bson.Binary{Subtype: bson.TypeBinaryVector, Data: []byte{0x03}})
+		}, true},
+		{"binary with an unknown vector type", func() (bson.Vector, error) {
+			return bson.NewVectorFromBinary(bson.Binary{Subtype: bson.TypeBinaryVector, Data: []byte{0x7F, 0x00}})
+		}, true},
+		{"int8 binary with nonzero padding", func() (bson.Vector, error) {
+			return bson.NewVectorFromBinary(bson.Binary{Subtype: bson.TypeBinaryVector, Data: []byte{bson.Int8Vector, 0x01, 0x05}})
+		}, true},
+		{"float32 binary with a partial value", func() (bson.Vector, error) {
+			return bson.NewVectorFromBinary(bson.Binary{Subtype: bson.TypeBinaryVector, Data: []byte{bson.Float32Vector, 0x00, 0x01, 0x02}})
+		}, true},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			v, err := c.build()
+			if (err != nil) != c.wantErr {
+				t.Fatalf("got error %v, want error %v", err, c.wantErr)
+			}
+			if err != nil {
+				return
+			}
+
+			decoded, err := bson.NewVectorFromBinary(v.Binary())
+			if err != nil {
+				t.Fatal(err)
+			}
+			data, padding := decoded.PackedBit()
+			wantData, wantPadding := v.PackedBit()
+			if !bytes.Equal(data, wantData) || padding != wantPadding {
+				t.Errorf("got %08b with padding %d, want %08b with padding %d", data, padding, wantData, wantPadding)
+			}
+		})
+	}
+}
+
+func TestHamming(t *testing.T) {
+	if n := hamming([]byte{0b10110000, 0xFF}, []byte{0b00110001, 0x0F}); n != 6 {
+		t.Errorf("got %d, want 6", n)
+	}
+}
+
+func TestOverlap(t *testing.T) {
+	if got := overlap([]int{1, 2, 3, 4}, []int{4, 5, 1, 6}); got != 0.5 {
+		t.Errorf("got %v, want 0.5", got)
+	}
+}
@@ -7,6 +7,7 @@ import (
 	"fmt"
 	"log"
 	"os"
+	"strings"
 	"time"
 
 	"github.com/joho/godotenv"
@@ -27,7 +28,90 @@ type BlogPost struct {
 
 // end struct
 
+// begin tag options
+// Stores the author as a single "Last, First" string by implementing
+// bson.Marshaler and bson.Unmarshaler
+type Author struct {
+	First string
+	Last  string
+}
+
+func (a Author) MarshalBSON() ([]byte, error) {
+	return bson.Marshal(bson.D{{"name", a.Last + ", " + a.First}})
+}
+
+func (a *Author) UnmarshalBSON(data []byte) error {
+	var doc struct {
+		Name string `bson:"name"`
+	}
+	if err := bson.Unmarshal(data, &doc); err != nil {
+		return err
+	}
+	last, first, found := strings.Cut(doc.Name, ", ")
+	if !found {
+		return fmt.Errorf("author name %q is not in the form \"Last, First\"", doc.Name)
+	}
+	a.First, a.Last = first, last
+	return nil
+}
+
+// Stores a duration as a readable string such as "4m30s" instead of a
+// number of nanoseconds by implementing bson.ValueMarshaler and
+// bson.ValueUnmarshaler
+type ReadingTime time.Duration
+
+func (r ReadingTime) MarshalBSONValue() (byte, []byte, error) {
+	typ, data, err := bson.MarshalValue(time.Duration(r).String())
+	return byte(typ), data, err
+}
+
+func (r *ReadingTime) UnmarshalBSONValue(typ byte, data []byte) error {
+	var s string
+	if err := bson.UnmarshalValue(bson.Type(typ), data, &s); err != nil {
+		return err
+	}
+	d, err := time.ParseDuration(s)
+	if err != nil {
+		return err
+	}
+	*r = ReadingTime(d)
+	return nil
+}
+
+// Holds fields that several document types share
+type Audit struct {
+	CreatedBy string `bson:"created_by"`
+	Revision  int64  `bson:"revision,minsize"`
+}
+
+type TaggedPost struct {
+	// Omits the zero ObjectID so that the server generates an _id value
+	ID    bson.ObjectID `bson:"_id,omitempty"`
+	Title string        `bson:"title"`
+	// Omits empty strings and nil or empty slices
+	Subtitle string   `bson:"subtitle,omitempty"`
+	Tags     []string `bson:"tags,omitempty"`
+	Author   Author   `bson:"author"`
+	// Stores values that fit in 32 bits as int32 instead of int64
+	WordCount int64 `bson:"word_count,minsize"`
+	Views     int64 `bson:"views"`
+	// Accepts a double such as 4.5 when decoding and drops its fraction
+	Rating   int         `bson:"rating,truncate"`
+	ReadTime ReadingTime `bson:"read_time"`
+	// Never stores this field
+	Draft string `bson:"-"`
+	// Stores the fields of Audit at the top level of the document
+	Audit `bson:",inline"`
+	// Collects fields that no other struct field matches
+	Extra map[string]interface{} `bson:",inline"`
+}
+
+// end tag options
+
 func main() {
+	fmt.Println("Tag Options:")
+	printTaggedPost()
+
 	if err := godotenv.Load(); err != nil {
 		log.Println("No .env file found")
 	}
@@ -85,3 +169,31 @@ func main() {
 	}
 	fmt.Printf("%s\n", output)
 }
+
+// Prints the document that the struct tag options produce for a TaggedPost
+func printTaggedPost() {
+	// begin encode tagged post
+	post := TaggedPost{
+		Title:     "Pruning Roses",
+		Author:    Author{First: "Sam", Last: "Lee"},
+		WordCount: 1240,
+		Views:     310,
+		Rating:    4,
+		ReadTime:  ReadingTime(4*time.Minute + 30*time.Second),
+		Draft:     "unpublished notes",
+		Audit:     Audit{CreatedBy: "editor", Revision: 3},
+		Extra:     map[string]interface{}{"season": "spring"},
+	}
+
+	data, err := bson.Marshal(post)
+	if err != nil {
+		panic(err)
+	}
+	// end encode tagged post
+
+	output, err := bson.MarshalExtJSONIndent(bson.Raw(data), true, false, "", "    ")
+	if err != nil {
+		panic(err)
+	}
+	fmt.Printf("%s\n\n", output)
+}
@@ -0,0 +1,109 @@
+// Tests the documents that the struct tag options produce against golden
+// Extended JSON strings. Run with:
+//
+//	go test struct-tag.go struct-tag_test.go
+package main
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+func TestEncodeTaggedPost(t *testing.T) {
+	post := TaggedPost{
+		Title:     "Pruning Roses",
+		Author:    Author{First: "Sam", Last: "Lee"},
+		WordCount: 1240,
+		Views:     310,
+		Rating:    4,
+		ReadTime:  ReadingTime(4*time.Minute + 30*time.Second),
+		Draft:     "unpublished notes",
+		Audit:     Audit{CreatedBy: "editor", Revision: 3},
+		Extra:     map[string]interface{}{"season": "spring"},
+	}
+
+	data, err := bson.Marshal(post)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := bson.MarshalExtJSON(bson.Raw(data), true, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// "word_count" and "revision" are int32 because of minsize, while
+	// "views" stays int64. "_id", "subtitle", "tags", and "Draft" are absent.
+	golden := `{"title":"Pruning Roses","author":{"name":"Lee, Sam"},"word_count":{"$numberInt":"1240"},` +
+		`"views":{"$numberLong":"310"},"rating":{"$numberInt":"4"},"read_time":"4m30s",` +
+		`"created_by":"editor","revision":{"$numberInt":"3"},"season":"spring"}`
+	if string(got) != golden {
+		t.Errorf("encoded TaggedPost differs from golden output\ngot:  %s\nwant: %s", got, golden)
+	}
+}
+
+// Decodes a document with a fractional rating and a field that the struct
+// does not declare
+func decodeInput(t *testing.T) bson.Raw {
+	t.Helper()
+	input := `{"_id":{"$oid":"65f1c0ffee0000000000beef"},"title":"Pruning Roses","tags":["roses"],` +
+		`"author":{"name":"Lee, Sam"},"word_count":{"$numberInt":"1240"},"views":{"$numberLong":"310"},` +
+		`"rating":{"$numberDouble":"4.5"},"read_time":"4m30s","created_by":"editor",` +
+		`"revision":{"$numberInt":"3"},"season":"spring","Draft":"ignored"}`
+
+	var doc bson.Raw
+	if err := bson.UnmarshalExtJSON([]byte(input), true, &doc); err != nil {
+		t.Fatal(err)
+	}
+	return doc
+}
+
+func TestDecodeTaggedPost(t *testing.T) {
+	var decoded TaggedPost
+	if err := bson.Unmarshal(decodeInput(t), &decoded); err != nil {
+		t.Fatal(err)
+	}
+
+	id, _ := bson.ObjectIDFromHex("65f1c0ffee0000000000beef")
+	want := TaggedPost{
+		ID:        id,
+		Title:     "Pruning Roses",
+		Tags:      []string{"roses"},
+		Author:    Author{First: "Sam", Last: "Lee"},
+		WordCount: 1240,
+		Views:     310,
+		Rating:    4,
+		ReadTime:  ReadingTime(4*time.Minute + 30*time.Second),
+		Audit:     Audit{CreatedBy: "editor", Revision: 3},
+		// The "-" tag also skips Draft when decoding, so the inline map
+		// collects the "Draft" field
+		Extra: map[string]interface{}{"season": "spring", "Draft": "ignored"},
+	}
+	if !reflect.DeepEqual(decoded, want) {
+		t.Errorf("decoded TaggedPost differs from golden value\ngot:  %+v\nwant: %+v", decoded, want)
+	}
+}
+
+// Without truncate, decoding a double with a fraction into an int fails
+func TestRejectFractionWithoutTruncate(t *testing.T) {
+	var strict struct {
+		Rating int `bson:"rating"`
+	}
+	if err := bson.Unmarshal(decodeInput(t), &strict); err == nil {
+		t.Error("decoding a fractional rating without truncate succeeded")
+	}
+}
+
+// UnmarshalBSON reports author names that it cannot parse
+func TestRejectMalformedAuthor(t *testing.T) {
+	bad, err := bson.Marshal(bson.D{{"author", bson.D{{"name", "Sam Lee"}}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var decoded TaggedPost
+	if err = bson.Unmarshal(bad, &decoded); err == nil {
+		t.Error("decoding a malformed author name succeeded")
+	}
+}